@@ -1,7 +1,19 @@
 package podman
 
+import (
+	"fmt"
+	"strings"
+)
+
 // packersdk.Artifact implementation
 type Artifact struct {
+	// ImageId is the ID of the committed image, if the container was
+	// committed.
+	ImageId string
+	// SavePath is the archive the committed image was saved to, if any.
+	SavePath string
+	// ExportPath is the file the container was exported to, if any.
+	ExportPath string
 	// StateData should store data such as GeneratedData
 	// to be shared with post-processors
 	StateData map[string]interface{}
@@ -12,15 +24,32 @@ func (*Artifact) BuilderId() string {
 }
 
 func (a *Artifact) Files() []string {
-	return []string{}
+	var files []string
+	if a.SavePath != "" {
+		files = append(files, a.SavePath)
+	}
+	if a.ExportPath != "" {
+		files = append(files, a.ExportPath)
+	}
+	return files
 }
 
-func (*Artifact) Id() string {
-	return ""
+func (a *Artifact) Id() string {
+	return a.ImageId
 }
 
 func (a *Artifact) String() string {
-	return ""
+	var parts []string
+	if a.ImageId != "" {
+		parts = append(parts, fmt.Sprintf("Committed image: %s", a.ImageId))
+	}
+	if a.SavePath != "" {
+		parts = append(parts, fmt.Sprintf("Saved image: %s", a.SavePath))
+	}
+	if a.ExportPath != "" {
+		parts = append(parts, fmt.Sprintf("Exported file: %s", a.ExportPath))
+	}
+	return strings.Join(parts, "\n")
 }
 
 func (a *Artifact) State(name string) interface{} {
@@ -0,0 +1,31 @@
+package podman
+
+import (
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestArtifact_impl(t *testing.T) {
+	var _ packersdk.Artifact = new(Artifact)
+}
+
+func TestArtifact(t *testing.T) {
+	a := &Artifact{
+		ImageId:    "sha256:foo",
+		SavePath:   "image.tar",
+		ExportPath: "rootfs.tar",
+	}
+
+	if a.Id() != "sha256:foo" {
+		t.Fatalf("bad: %#v", a.Id())
+	}
+	if files := a.Files(); len(files) != 2 || files[0] != "image.tar" || files[1] != "rootfs.tar" {
+		t.Fatalf("bad: %#v", files)
+	}
+
+	expected := "Committed image: sha256:foo\nSaved image: image.tar\nExported file: rootfs.tar"
+	if a.String() != expected {
+		t.Fatalf("bad: %#v", a.String())
+	}
+}
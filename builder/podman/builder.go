@@ -2,9 +2,11 @@ package podman
 
 import (
 	"context"
+	"fmt"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 	"log"
+	"strconv"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
@@ -29,11 +31,13 @@ func (b *Builder) Prepare(raws ...interface{}) (generatedVars []string, warnings
 
 	return []string{
 		"ImageSha256",
+		"SourceImageDigest",
+		"Rootless",
 	}, warnings, nil
 }
 
 func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
-	driver := &PodmanDriver{Ctx: &b.config.ctx, Ui: ui}
+	driver := &PodmanDriver{Ui: ui}
 	if err := driver.Verify(); err != nil {
 		return nil, err
 	}
@@ -44,13 +48,33 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 	state.Put("ui", ui)
 	generatedData := &packerbuilderdata.GeneratedData{State: state}
 
+	info, err := driver.PodmanInfo()
+	if err != nil {
+		return nil, err
+	}
+	generatedData.Put("Rootless", strconv.FormatBool(info.Rootless))
+	b.config.applyPodmanInfo(info)
+	if info.Rootless {
+		log.Print("[DEBUG] Podman runs rootless")
+		for _, warning := range b.config.rootlessWarnings() {
+			ui.Message(fmt.Sprintf("Warning: %s", warning))
+		}
+	}
+
 	// Setup the driver that will talk to Podman
 	state.Put("driver", driver)
 
 	steps := []multistep.Step{
 		&StepTempDir{},
-		&StepPull{},
+		&StepPreflight{},
+		&StepPull{ // Adds SourceImageDigest variable available after StepPull
+			GeneratedData: generatedData,
+		},
+		&StepServices{},
+		&StepSecrets{},
+		&StepCacheMounts{},
 		&StepRun{},
+		&StepWaitReady{},
 		&communicator.StepConnect{
 			Config:    &b.config.Comm,
 			Host:      commHost(b.config.Comm.Host()),
@@ -67,17 +91,32 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 
 	if b.config.Discard {
 		log.Print("[DEBUG] Container will be discarded")
-	} else if b.config.Commit {
-		log.Print("[DEBUG] Container will be committed")
-		steps = append(steps, &StepSetDefaults{})
-		steps = append(steps,
-			new(StepCommit),
-			&StepSetGeneratedData{ // Adds ImageSha256 variable available after StepCommit
-				GeneratedData: generatedData,
-			})
-	} else if b.config.ExportPath != "" {
-		log.Printf("[DEBUG] Container will be exported to %s", b.config.ExportPath)
-		steps = append(steps, new(StepExport))
+	} else if b.config.Commit || b.config.ExportPath != "" {
+		if b.config.StopBeforeCommit {
+			log.Print("[DEBUG] Container will be stopped before snapshotting")
+			steps = append(steps, new(StepStop))
+		}
+		if len(b.config.Secrets) > 0 {
+			log.Print("[DEBUG] Container will be checked for secrets")
+			steps = append(steps, new(StepVerifySecrets))
+		}
+		if b.config.Commit {
+			log.Print("[DEBUG] Container will be committed")
+			steps = append(steps, &StepSetDefaults{})
+			steps = append(steps,
+				new(StepCommit),
+				&StepSetGeneratedData{ // Adds ImageSha256 variable available after StepCommit
+					GeneratedData: generatedData,
+				})
+			if b.config.SavePath != "" {
+				log.Printf("[DEBUG] Image will be saved to %s", b.config.SavePath)
+				steps = append(steps, new(StepSave))
+			}
+		}
+		if b.config.ExportPath != "" {
+			log.Printf("[DEBUG] Container will be exported to %s", b.config.ExportPath)
+			steps = append(steps, new(StepExport))
+		}
 	} else {
 		return nil, errArtifactNotUsed
 	}
@@ -88,6 +127,13 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 
 	// If there was an error, return that
 	if err, ok := state.GetOk("error"); ok {
+		// The container is only still around if cleanup was skipped, either
+		// because of keep_container_on_error or -on-error=abort.
+		if containerId, ok := state.GetOk("container_id"); ok {
+			ui.Say(fmt.Sprintf("Container kept for debugging: %s", containerId))
+			ui.Message(fmt.Sprintf("Open a shell in it with: podman exec -it %s /bin/sh", containerId))
+			ui.Message(fmt.Sprintf("Remove it when done with: podman rm -f %s", containerId))
+		}
 		return nil, err.(error)
 	}
 
@@ -96,5 +142,10 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 		// can access them.
 		StateData: map[string]interface{}{"generated_data": state.Get("generated_data")},
 	}
+	if imageId, ok := state.GetOk("image_id"); ok {
+		artifact.ImageId = imageId.(string)
+	}
+	artifact.SavePath = b.config.SavePath
+	artifact.ExportPath = b.config.ExportPath
 	return artifact, nil
 }
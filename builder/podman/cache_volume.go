@@ -0,0 +1,58 @@
+package podman
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// cacheVolumeLabel is the label of the cache volumes, its value is the name
+// of the build that created them. cacheScopeLabel tells the templates
+// sharing build names apart, its value is a hash of cache_scope.
+const (
+	cacheVolumeLabel = "io.packer.podman.cache"
+	cacheScopeLabel  = "io.packer.podman.cache.scope"
+)
+
+// Volume is what `podman volume ls` reports about a named volume.
+type Volume struct {
+	Name      string
+	CreatedAt time.Time
+	Labels    map[string]string
+}
+
+// parseVolumes parses the output of `podman volume ls --format json`.
+func parseVolumes(output []byte) ([]Volume, error) {
+	var volumes []Volume
+	if len(bytes.TrimSpace(output)) == 0 {
+		return volumes, nil
+	}
+	if err := json.Unmarshal(output, &volumes); err != nil {
+		return nil, fmt.Errorf("Error parsing podman volume ls: %s", err)
+	}
+	return volumes, nil
+}
+
+// cacheVolumeLabels returns the labels of the cache volumes of a build of
+// the template identified by scope.
+func cacheVolumeLabels(scope, buildName string) map[string]string {
+	sum := sha256.Sum256([]byte(scope))
+	return map[string]string{
+		cacheVolumeLabel: buildName,
+		cacheScopeLabel:  hex.EncodeToString(sum[:8]),
+	}
+}
+
+// staleVolumes returns the names of the volumes created before cutoff.
+func staleVolumes(volumes []Volume, cutoff time.Time) []string {
+	var names []string
+	for _, v := range volumes {
+		if v.CreatedAt.Before(cutoff) {
+			names = append(names, v.Name)
+		}
+	}
+	return names
+}
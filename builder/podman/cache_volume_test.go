@@ -0,0 +1,79 @@
+package podman
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseVolumes(t *testing.T) {
+	output := `[
+  {
+    "Name": "dnf-cache",
+    "Driver": "local",
+    "Mountpoint": "/home/packer/.local/share/containers/storage/volumes/dnf-cache/_data",
+    "CreatedAt": "2024-03-01T10:20:30.123456789+01:00",
+    "Labels": {
+      "io.packer.podman.cache": "podman.example"
+    },
+    "Scope": "local"
+  }
+]`
+
+	volumes, err := parseVolumes([]byte(output))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(volumes) != 1 || volumes[0].Name != "dnf-cache" {
+		t.Fatalf("bad: %#v", volumes)
+	}
+	if volumes[0].CreatedAt.UTC() != time.Date(2024, 3, 1, 9, 20, 30, 123456789, time.UTC) {
+		t.Fatalf("bad: %s", volumes[0].CreatedAt)
+	}
+	if volumes[0].Labels[cacheVolumeLabel] != "podman.example" {
+		t.Fatalf("bad: %#v", volumes[0].Labels)
+	}
+
+	// No volume at all
+	volumes, err = parseVolumes([]byte("\n"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(volumes) != 0 {
+		t.Fatalf("bad: %#v", volumes)
+	}
+
+	if _, err := parseVolumes([]byte("nope")); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestCacheVolumeLabels(t *testing.T) {
+	a := cacheVolumeLabels("/templates/a", "podman.example")
+	if a[cacheVolumeLabel] != "podman.example" || a[cacheScopeLabel] == "" {
+		t.Fatalf("bad: %#v", a)
+	}
+
+	// The same build name in another template
+	b := cacheVolumeLabels("/templates/b", "podman.example")
+	if a[cacheScopeLabel] == b[cacheScopeLabel] {
+		t.Fatalf("bad: %#v %#v", a, b)
+	}
+
+	if !reflect.DeepEqual(a, cacheVolumeLabels("/templates/a", "podman.example")) {
+		t.Fatalf("bad: %#v", a)
+	}
+}
+
+func TestStaleVolumes(t *testing.T) {
+	now := time.Now()
+	volumes := []Volume{
+		{Name: "old", CreatedAt: now.Add(-48 * time.Hour)},
+		{Name: "new", CreatedAt: now.Add(-time.Hour)},
+	}
+
+	stale := staleVolumes(volumes, now.Add(-24*time.Hour))
+	if !reflect.DeepEqual(stale, []string{"old"}) {
+		t.Fatalf("bad: %#v", stale)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -25,6 +26,7 @@ type Communicator struct {
 	Version       *version.Version
 	Config        *Config
 	ContainerUser string
+	Rootless      bool
 	lock          sync.Mutex
 	EntryPoint    []string
 }
@@ -32,22 +34,7 @@ type Communicator struct {
 var _ packersdk.Communicator = new(Communicator)
 
 func (c *Communicator) Start(ctx context.Context, remote *packersdk.RemoteCmd) error {
-	podmanArgs := []string{
-		"exec",
-		"-i",
-		c.ContainerID,
-	}
-	podmanArgs = append(podmanArgs, c.EntryPoint...)
-	podmanArgs = append(podmanArgs, fmt.Sprintf("(%s)", remote.Command))
-
-	if c.Config.Pty {
-		podmanArgs = append(podmanArgs[:2], append([]string{"-t"}, podmanArgs[2:]...)...)
-	}
-
-	if c.Config.ExecUser != "" {
-		podmanArgs = append(podmanArgs[:2],
-			append([]string{"-u", c.Config.ExecUser}, podmanArgs[2:]...)...)
-	}
+	podmanArgs := c.execArgs(remote.Command)
 
 	cmd := exec.Command("podman", podmanArgs...)
 
@@ -77,6 +64,23 @@ func (c *Communicator) Start(ctx context.Context, remote *packersdk.RemoteCmd) e
 	return nil
 }
 
+// execArgs returns the arguments of the podman exec session running command.
+func (c *Communicator) execArgs(command string) []string {
+	podmanArgs := []string{"exec", "-i"}
+	if c.Config.Pty {
+		podmanArgs = append(podmanArgs, "-t")
+	}
+	if c.Config.ExecUser != "" {
+		podmanArgs = append(podmanArgs, "-u", c.Config.ExecUser)
+	}
+	for _, env := range execEnv(c.Config) {
+		podmanArgs = append(podmanArgs, "--env", env)
+	}
+	podmanArgs = append(podmanArgs, c.ContainerID)
+	podmanArgs = append(podmanArgs, c.EntryPoint...)
+	return append(podmanArgs, fmt.Sprintf("(%s)", command))
+}
+
 // Upload uploads a file to the podman container
 func (c *Communicator) Upload(dst string, src io.Reader, fi *os.FileInfo) error {
 	if fi == nil {
@@ -346,14 +350,9 @@ func (c *Communicator) fixDestinationOwner(destination string) error {
 		return nil
 	}
 
-	owner := c.ContainerUser
-	if owner == "" {
-		owner = "root"
-	}
-
 	chownArgs := []string{
 		"podman", "exec", "--user", "root", c.ContainerID, "/bin/sh", "-c",
-		fmt.Sprintf("chown -R %s %s", owner, destination),
+		fmt.Sprintf("chown -R %s %s", c.uploadOwner(), destination),
 	}
 	if output, err := exec.Command(chownArgs[0], chownArgs[1:]...).CombinedOutput(); err != nil {
 		return fmt.Errorf("Failed to set owner of the uploaded file: %s, %s", err, output)
@@ -361,3 +360,37 @@ func (c *Communicator) fixDestinationOwner(destination string) error {
 
 	return nil
 }
+
+// uploadOwner returns the owner uploaded files are given.
+func (c *Communicator) uploadOwner() string {
+	if c.ContainerUser != "" {
+		return c.ContainerUser
+	}
+
+	// With keep-id, rootless podman runs the container as the user running
+	// Packer rather than as root, or as the uid and gid options of keep-id.
+	if c.Rootless && (c.Config.Userns == "keep-id" || strings.HasPrefix(c.Config.Userns, "keep-id:")) {
+		uid, gid := keepIDOwner(c.Config.Userns)
+		return fmt.Sprintf("%s:%s", uid, gid)
+	}
+
+	return "root"
+}
+
+// keepIDOwner returns the uid and gid a keep-id user namespace, such as
+// keep-id:uid=1000,gid=1000, maps the user running Packer to. Each defaults
+// to the one of the user running Packer.
+func keepIDOwner(userns string) (uid, gid string) {
+	uid, gid = strconv.Itoa(os.Getuid()), strconv.Itoa(os.Getgid())
+	if i := strings.Index(userns, ":"); i != -1 {
+		for _, option := range strings.Split(userns[i+1:], ",") {
+			switch {
+			case strings.HasPrefix(option, "uid="):
+				uid = strings.TrimPrefix(option, "uid=")
+			case strings.HasPrefix(option, "gid="):
+				gid = strings.TrimPrefix(option, "gid=")
+			}
+		}
+	}
+	return uid, gid
+}
@@ -0,0 +1,62 @@
+package podman
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// exportCompressionLevels maps every supported export_compression value to
+// the highest level it accepts.
+var exportCompressionLevels = map[string]int{
+	"":     0,
+	"gzip": gzip.BestCompression,
+	"zstd": 22,
+	"xz":   9,
+}
+
+// xzDictCaps mirrors the dictionary sizes used by the xz(1) presets. The xz
+// library only exposes the dictionary capacity as a tunable, so the level of
+// xz only picks the dictionary size of the preset, not its effort.
+var xzDictCaps = []int{
+	256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20,
+	8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20,
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newCompressWriter wraps dst so that everything written to the returned
+// writer is compressed with the given algorithm. A level of 0 selects the
+// algorithm default. The returned writer must be closed to flush the stream.
+func newCompressWriter(dst io.Writer, algorithm string, level int) (io.WriteCloser, error) {
+	switch algorithm {
+	case "":
+		return nopWriteCloser{dst}, nil
+	case "gzip":
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(dst, level)
+	case "zstd":
+		var opts []zstd.EOption
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		return zstd.NewWriter(dst, opts...)
+	case "xz":
+		var cfg xz.WriterConfig
+		if level != 0 {
+			cfg.DictCap = xzDictCaps[level]
+		}
+		return cfg.NewWriter(dst)
+	}
+
+	return nil, fmt.Errorf("unknown compression algorithm: %s", algorithm)
+}
@@ -1,5 +1,5 @@
 //go:generate packer-sdc struct-markdown
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,MountConfig,RegistryAuth,SecretConfig,ServiceConfig
 
 package podman
 
@@ -11,14 +11,34 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/mitchellh/mapstructure"
+	"io/ioutil"
+	"log"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 )
 
 var (
-	errArtifactNotUsed     = fmt.Errorf("No instructions given for handling the artifact; expected commit, discard, or export_path")
-	errArtifactUseConflict = fmt.Errorf("Cannot specify more than one of commit, discard, and export_path")
-	errExportPathNotFile   = fmt.Errorf("export_path must be a file, not a directory")
-	errImageNotSpecified   = fmt.Errorf("Image must be specified")
+	errArtifactNotUsed          = fmt.Errorf("No instructions given for handling the artifact; expected commit, discard, or export_path")
+	errArtifactUseConflict      = fmt.Errorf("Cannot specify discard together with commit or export_path")
+	errCachePruneAfterNegative  = fmt.Errorf("cache_prune_after must not be negative")
+	errNoSSHAgent               = fmt.Errorf("forward_ssh_agent requires SSH_AUTH_SOCK to be set")
+	errExportPathNotFile        = fmt.Errorf("export_path must be a file, not a directory")
+	errExportLevelNoCompression = fmt.Errorf("export_compression_level requires export_compression to be set")
+	errExportLevelSquashfsXz    = fmt.Errorf("export_compression_level is not supported for xz compressed squashfs exports")
+	errImageNotSpecified        = fmt.Errorf("Image must be specified")
+	errLoginPasswordConflict    = fmt.Errorf("Cannot specify both login_password and login_password_file")
+	errPullConflict             = fmt.Errorf("Cannot specify both pull and pull_policy")
+	errReadyConflict            = fmt.Errorf("Cannot specify both ready_command and ready_healthcheck")
+	errReadyTimeoutNegative     = fmt.Errorf("ready_timeout must not be negative")
+	errPullRetriesNegative      = fmt.Errorf("pull_retries must not be negative")
+	errRegistryAuthConflict     = fmt.Errorf("Cannot specify registry_auth together with login or authfile")
+	errImageDigestConflict      = fmt.Errorf("image_digest doesn't match the digest in image")
+	errImageDigestInvalid       = fmt.Errorf("image_digest must be of the form sha256:<64 hex characters>")
+	errVerifyNoDigest           = fmt.Errorf("verify_base_digest requires image_digest or an image pinned by digest")
+	errSavePathNoCommit         = fmt.Errorf("save_path requires commit to be set")
+	errSavePathNotFile          = fmt.Errorf("save_path must be a file, not a directory")
 )
 
 // Config for packer arguments. Shamelessly taken from packer-plugin-docker with
@@ -33,15 +53,34 @@ type Config struct {
 	// are CMD, ENTRYPOINT, ENV, and EXPOSE. Example: [ "USER ubuntu", "WORKDIR
 	// /app", "EXPOSE 8080" ]
 	Changes []string `mapstructure:"changes"`
-	// If true, the container will be committed to an image rather than exported.
+	// The instructions of the base image configuration to carry over to the
+	// committed image, among CMD, ENTRYPOINT, ENV, EXPOSE, HEALTHCHECK,
+	// LABEL, STOPSIGNAL, USER and WORKDIR. Instructions set in `changes`
+	// take precedence. Defaults to `["CMD", "ENTRYPOINT"]`, which undoes the
+	// entrypoint `run_command` sets; set it to `[]` to inherit nothing.
+	InheritConfig []string `mapstructure:"inherit_config" required:"false"`
+	// If true, the container will be committed to an image. This can be
+	// combined with `export_path` to also export the container filesystem.
 	Commit bool `mapstructure:"commit" required:"true"`
+	// If true, the container is paused while it is committed, so that its
+	// filesystem doesn't change halfway through. This defaults to true if not
+	// set, except when podman runs rootless on a cgroup v1 host, where
+	// containers can't be paused.
+	CommitPause bool `mapstructure:"commit_pause" required:"false"`
+	// commitPauseDefaulted is true when commit_pause wasn't set, so that it
+	// can be turned off on hosts that can't pause containers.
+	commitPauseDefaulted bool
 
 	// The directory inside container to mount temp directory from host server
 	// for work [file provisioner](/docs/provisioners/file). This defaults
-	// to c:/packer-files on windows and /packer-files on other systems.
+	// to c:/packer-files on windows and /packer-files on other systems. The
+	// directory is mounted with a private SELinux label, so that the
+	// container can use it on hosts enforcing SELinux.
 	ContainerDir string `mapstructure:"container_dir" required:"false"`
 	// An array of devices which will be accessible in container when it's run
-	// without `--privileged` flag.
+	// without `--privileged` flag, in the `host[:container][:permissions]`
+	// format, for example `/dev/fuse` or `/dev/sdc:/dev/xvdc:rwm`, or CDI device
+	// names such as `nvidia.com/gpu=all`.
 	Device []string `mapstructure:"device" required:"false"`
 	// Throw away the container when the build is complete. This is useful for
 	// the [artifice
@@ -59,27 +98,97 @@ type Config struct {
 	// name/ID if you want: (UID or UID:GID). You may need this if you get
 	// permission errors trying to run the shell or other provisioners.
 	ExecUser string `mapstructure:"exec_user" required:"false"`
-	// The path where the final container will be exported as a tar file.
+	// The path where the final container will be exported as a tar file. This
+	// can be combined with `commit` to get both an image and an export out of
+	// the same build.
 	ExportPath string `mapstructure:"export_path" required:"true"`
+	// The format of the file written to `export_path`. `tar` (the default)
+	// writes the container filesystem as a plain tar archive, `oci-layout`
+	// writes the container as an OCI image layout packed in a tar archive and
+	// `squashfs` writes the container filesystem as a squashfs image. The
+	// latter requires `sqfstar` from squashfs-tools 4.6 or newer.
+	ExportFormat string `mapstructure:"export_format" required:"false"`
+	// Compress the exported file with `gzip`, `zstd` or `xz`. By default the
+	// export is not compressed. For `squashfs` exports the compression is
+	// applied by `sqfstar` to the filesystem image itself.
+	ExportCompression string `mapstructure:"export_compression" required:"false"`
+	// The level used by `export_compression`: 1-9 for `gzip` and 1-22 for
+	// `zstd`. For `xz`, 1-9 only picks the dictionary size of the matching
+	// `xz` preset, from 256 KiB to 64 MiB, not the compression effort.
+	// Defaults to the default level of the chosen algorithm.
+	ExportCompressionLevel int `mapstructure:"export_compression_level" required:"false"`
 	// The base image for the Podman container that will be started. This image
 	// will be pulled from the Podman registry if it doesn't already exist.
+	// The image can be pinned by digest with the `image@sha256:...` form.
 	Image string `mapstructure:"image" required:"true"`
+	// Pin `image` to this digest, for example `sha256:...`. The image is then
+	// pulled and run by digest, so that the same base is used regardless of
+	// where its tag points to.
+	ImageDigest string `mapstructure:"image_digest" required:"false"`
+	// If true, fail the build when the base image in local storage doesn't
+	// match the digest from `image_digest` or `image`. This also guards
+	// against a stale or tampered local image when `pull_policy` isn't
+	// `always`.
+	VerifyBaseDigest bool `mapstructure:"verify_base_digest" required:"false"`
 	// Set a message for the commit.
 	Message string `mapstructure:"message" required:"true"`
+	// If true, the container is stopped before it is committed or exported,
+	// so that services such as journald or databases are not caught
+	// mid-write. The container is given `stop_timeout` to shut down.
+	StopBeforeCommit bool `mapstructure:"stop_before_commit" required:"false"`
+	// The path where the committed image will be saved as an archive using
+	// `podman save`. Requires `commit` to be set.
+	SavePath string `mapstructure:"save_path" required:"false"`
 	// If true, run the Podman container with the `--privileged` flag. This
 	// defaults to false if not set.
 	Privileged bool `mapstructure:"privileged" required:"false"`
 	Pty        bool
-	// If true, the configured image will be pulled using `podman pull` prior
-	// to use. Otherwise, it is assumed the image already exists and can be
-	// used. This defaults to true if not set.
+	// Deprecated: use `pull_policy` instead. Setting this to false is the
+	// same as setting `pull_policy` to `never`.
 	Pull bool `mapstructure:"pull" required:"false"`
+	// When to pull the configured image with `podman pull` before starting
+	// the container. `always` (the default) pulls on every build, `missing`
+	// only pulls if the image isn't in local storage, `never` assumes the
+	// image already exists and `newer` only pulls if the registry serves a
+	// different image than the local one, falling back to the local image if
+	// the registry can't be reached. `newer` checks the registry with `podman
+	// manifest inspect`, which doesn't take `cert_dir` into account.
+	PullPolicy string `mapstructure:"pull_policy" required:"false"`
+	// The platform to pull the image for, in the `os/arch[/variant]` form,
+	// for example `linux/arm64`. Defaults to the platform of the host.
+	PullPlatform string `mapstructure:"pull_platform" required:"false"`
+	// Require HTTPS and verify certificates when pulling the image. Defaults
+	// to the podman configuration of the registry.
+	TLSVerify config.Trilean `mapstructure:"tls_verify" required:"false"`
+	// Path to a directory with the certificates (`*.crt`, `*.cert`, `*.key`)
+	// used to connect to the registry.
+	CertDir string `mapstructure:"cert_dir" required:"false"`
+	// Path to the authentication file used to pull the image, in the format
+	// written by `podman login`. Defaults to the podman default auth file.
+	AuthFile string `mapstructure:"authfile" required:"false"`
+	// Credentials for the registries the image is pulled from. They are
+	// written to a temporary auth file, which is removed as soon as the
+	// image is pulled, so the credentials of the user are left untouched.
+	// This can't be combined with `login` or `authfile`.
+	RegistryAuth []RegistryAuth `mapstructure:"registry_auth" required:"false"`
+	// How many times a failed pull is retried before giving up. Defaults to
+	// 0.
+	PullRetries int `mapstructure:"pull_retries" required:"false"`
+	// How long to wait between pull attempts. Defaults to `5s`.
+	PullRetryDelay time.Duration `mapstructure:"pull_retry_delay" required:"false"`
+	// The key used to decrypt an encrypted image, as `key[:passphrase]`.
+	DecryptionKey string `mapstructure:"decryption_key" required:"false"`
 	// An array of arguments to pass to podman run in order to run the
 	// container. By default this is set to `["-d", "-i", "-t",
 	// "--entrypoint=/bin/sh", "--", "{{.Image}}"]` if you are using a linux
 	// container, and `["-d", "-i", "-t", "--entrypoint=powershell", "--",
 	// "{{.Image}}"]` if you are running a windows container. `{{.Image}}` is a
-	// template variable that corresponds to the image template option. Passing
+	// template variable that corresponds to the image template option. The
+	// other template variables are `{{.TempDir}}`, the host directory
+	// mounted at `{{.ContainerDir}}`, `{{.BuildName}}`, `{{.ContainerName}}`,
+	// a name unique to the build for use with `--name`, `{{.Platform}}`, the
+	// value of `pull_platform`, and `{{.RunVars.<name>}}` for the values of
+	// `run_vars`. Passing
 	// the entrypoint option this way will make it the default entrypoint of
 	// the resulting image, so running podman run -it --rm  will start the
 	// podman image from the /bin/sh shell interpreter; you could run a script
@@ -87,27 +196,109 @@ type Config struct {
 	// podman image embeds a binary intended to be run often, you should
 	// consider changing the default entrypoint to point to it.
 	RunCommand []string `mapstructure:"run_command" required:"false"`
-	// An array of additional tmpfs volumes to mount into this container.
+	// Values made available to `run_command` as `{{.RunVars.<name>}}`.
+	RunVars map[string]string `mapstructure:"run_vars" required:"false"`
+	// An array of additional tmpfs volumes to mount into this container, in
+	// the `path[:options]` format, for example `/run:rw,size=64m`.
 	TmpFs []string `mapstructure:"tmpfs" required:"false"`
 	// A mapping of additional volumes to mount into this container. The key of
 	// the object is the host path, the value is the container path.
 	Volumes map[string]string `mapstructure:"volumes" required:"false"`
+	// Mounts of the container, which unlike `volumes` take options, such as
+	// `ro` or `U`, and can be of another type than bind mounts. See
+	// [Mounts](#mounts).
+	Mounts []MountConfig `mapstructure:"mount" required:"false"`
+	// The user namespace mode of the container, passed to `podman run
+	// --userns`. With `keep-id`, rootless podman maps the user running
+	// Packer to the same UID inside the container, or to the `uid` and `gid`
+	// options of `keep-id`, so that files in `volumes` keep their ownership,
+	// and uploaded files are owned by that user unless the image sets another
+	// one.
+	Userns string `mapstructure:"userns" required:"false"`
 	// If true, files uploaded to the container will be owned by the user the
 	// container is running as. If false, the owner will depend on the version
 	// of podman installed in the system. Defaults to true.
 	FixUploadOwner bool `mapstructure:"fix_upload_owner" required:"false"`
+	// If true, the container is neither stopped nor removed when the build
+	// fails, so that it can be inspected. Its ID and the command to open a
+	// shell in it are printed at the end of the build. Running Packer with
+	// `-on-error=abort`, or choosing abort with `-on-error=ask`, has the same
+	// effect.
+	KeepContainerOnError bool `mapstructure:"keep_container_on_error" required:"false"`
+	// The signal sent to the container to stop it once the build is over, for
+	// example `SIGRTMIN+3` to shut down systemd cleanly. Defaults to the stop
+	// signal of the image.
+	StopSignal string `mapstructure:"stop_signal" required:"false"`
+	// How long to wait for the container to stop gracefully before killing
+	// it, rounded up to the second. Defaults to `10s`.
+	StopTimeout time.Duration `mapstructure:"stop_timeout" required:"false"`
+	// Service containers to start next to the build container while
+	// provisioning, for example a database integration tests need. See
+	// [Services](#services).
+	Services []ServiceConfig `mapstructure:"service" required:"false"`
+	// Secrets, such as the credentials of a package mirror, made available
+	// to the provisioners at `/run/secrets/<name>` but kept out of the
+	// image. See [Secrets](#secrets).
+	Secrets []SecretConfig `mapstructure:"secret" required:"false"`
+	// Named podman volumes to mount into the container as caches, for
+	// example of the packages downloaded by dnf or npm. The key is the name
+	// of the volume, created on first use, and the value is the container
+	// path, such as `/var/cache/dnf`. Cache volumes persist across builds and
+	// are neither committed nor exported. See [Cache Mounts](#cache-mounts).
+	CacheMounts map[string]string `mapstructure:"cache_mounts" required:"false"`
+	// Remove the cache volumes created by this build longer ago than this
+	// duration before starting it, so that they start afresh, for example
+	// `168h`. By default cache volumes are never removed.
+	CachePruneAfter time.Duration `mapstructure:"cache_prune_after" required:"false"`
+	// Identifies the template the cache volumes are created for, so that
+	// `cache_prune_after` only removes the volumes of this template and
+	// build. Defaults to the directory Packer runs in. Set it, for example
+	// to `path.root`, when templates sharing build names run from the same
+	// directory.
+	CacheScope string `mapstructure:"cache_scope" required:"false"`
+	// If true, the SSH agent of the host, found with `SSH_AUTH_SOCK`, is
+	// made available to the provisioners, for example to clone private git
+	// repositories. Defaults to false.
+	ForwardSSHAgent bool `mapstructure:"forward_ssh_agent" required:"false"`
+	// If true, the `HTTP_PROXY`, `HTTPS_PROXY` and `NO_PROXY` environment
+	// variables of the host, in upper or lower case, are set for the
+	// provisioners, but not committed into the image. Defaults to false.
+	ForwardProxyEnv bool `mapstructure:"forward_proxy_env" required:"false"`
+	// If true, don't check the podman host before the build: storage driver,
+	// free space, cgroup version and registry reachability. Defaults to
+	// false.
+	SkipPreflight bool `mapstructure:"skip_preflight" required:"false"`
 	// Enforce Podman in running in systemd mode. By default this value is set
-	// to `true`, but it can be `false` or `always`.
+	// to `true`, but it can be `false` or `always`. `always` requires podman
+	// 2.0 or newer.
 	// Please refer to Podman documentation for additional details
 	Systemd string `mapstructure:"systemd" required:"false"`
+	// A shell command run in the container until it succeeds before
+	// provisioning starts, for example `test -S /run/app.sock`. By default,
+	// provisioning waits for systemd to finish booting when it runs as the
+	// init of the container, and fails listing the failed units if the
+	// system is degraded.
+	ReadyCommand string `mapstructure:"ready_command" required:"false"`
+	// If true, provisioning starts once the health check of the image passes
+	// instead. This can't be combined with `ready_command`.
+	ReadyHealthcheck bool `mapstructure:"ready_healthcheck" required:"false"`
+	// How long to wait for the container to be ready before failing the
+	// build. Defaults to `2m`.
+	ReadyTimeout time.Duration `mapstructure:"ready_timeout" required:"false"`
 
 	// This is used to login to private registry to pull a base container.
 	Login bool `mapstructure:"login" required:"false"`
-	// The password to use to authenticate to login.
+	// The password to use to authenticate to login. Defaults to the
+	// `PODMAN_LOGIN_PASSWORD` environment variable.
 	LoginPassword string `mapstructure:"login_password" required:"false"`
+	// Path to a file holding the password to use to authenticate to login.
+	// A trailing newline is ignored. This can't be combined with
+	// `login_password`.
+	LoginPasswordFile string `mapstructure:"login_password_file" required:"false"`
 	// The server address to login to.
 	LoginServer string `mapstructure:"login_server" required:"false"`
-	// The username to use to authenticate to login.
+	// The username to use to authenticate to login. Defaults to the
+	// `PODMAN_LOGIN_USERNAME` environment variable.
 	LoginUsername string `mapstructure:"login_username" required:"false"`
 
 	ctx interpolate.Context
@@ -139,17 +330,27 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		c.RunCommand = []string{"-d", "-i", "-t", "--entrypoint=/bin/sh", "--", "{{.Image}}"}
 	}
 
-	// Default Pull if it wasn't set
-	hasPull := false
+	// Default CommitPause and InheritConfig if they weren't set, and find out
+	// whether the deprecated pull option is in use
+	hasPull, hasCommitPause, hasInheritConfig := false, false, false
 	for _, k := range md.Keys {
-		if k == "pull" {
+		switch k {
+		case "pull":
 			hasPull = true
-			break
+		case "commit_pause":
+			hasCommitPause = true
+		case "inherit_config":
+			hasInheritConfig = true
 		}
 	}
 
-	if !hasPull {
-		c.Pull = true
+	if !hasCommitPause {
+		c.CommitPause = true
+		c.commitPauseDefaulted = true
+	}
+
+	if !hasInheritConfig {
+		c.InheritConfig = []string{"CMD", "ENTRYPOINT"}
 	}
 
 	// Default to the normal Podman type
@@ -160,15 +361,81 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		c.Comm.Type = "docker"
 	}
 
+	var warnings []string
 	var errs *packersdk.MultiError
 	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
 		errs = packersdk.MultiErrorAppend(errs, es...)
 	}
+
+	if hasPull {
+		warnings = append(warnings, "pull is deprecated, use pull_policy instead")
+		if c.PullPolicy != "" {
+			errs = packersdk.MultiErrorAppend(errs, errPullConflict)
+		} else if c.Pull {
+			c.PullPolicy = "always"
+		} else {
+			c.PullPolicy = "never"
+		}
+	}
+
+	if c.PullPolicy == "" {
+		c.PullPolicy = "always"
+	}
+
+	if c.PullRetries < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errPullRetriesNegative)
+	}
+
+	if c.Login {
+		if es := c.prepareLogin(); len(es) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, es...)
+		}
+	}
+
+	if len(c.RegistryAuth) > 0 && (c.Login || c.AuthFile != "") {
+		errs = packersdk.MultiErrorAppend(errs, errRegistryAuthConflict)
+	}
+	for i := range c.RegistryAuth {
+		if es := c.RegistryAuth[i].Prepare(); len(es) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, es...)
+		}
+	}
+
+	if c.PullRetryDelay == 0 {
+		c.PullRetryDelay = 5 * time.Second
+	}
+
+	switch c.PullPolicy {
+	case "always", "missing", "never", "newer":
+	default:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+			"pull_policy must be one of always, missing, never or newer, got %q", c.PullPolicy))
+	}
 	if c.Image == "" {
 		errs = packersdk.MultiErrorAppend(errs, errImageNotSpecified)
 	}
 
-	if (c.ExportPath != "" && c.Commit) || (c.ExportPath != "" && c.Discard) || (c.Commit && c.Discard) {
+	// The digest may either come from image_digest or from the image itself,
+	// in both cases we end up with an image reference pinned by digest
+	if i := strings.LastIndex(c.Image, "@"); i != -1 {
+		digest := c.Image[i+1:]
+		if c.ImageDigest != "" && c.ImageDigest != digest {
+			errs = packersdk.MultiErrorAppend(errs, errImageDigestConflict)
+		}
+		c.ImageDigest = digest
+	} else if c.ImageDigest != "" && c.Image != "" {
+		c.Image = pinImageDigest(c.Image, c.ImageDigest)
+	}
+
+	if c.ImageDigest != "" && !digestRegexp.MatchString(c.ImageDigest) {
+		errs = packersdk.MultiErrorAppend(errs, errImageDigestInvalid)
+	}
+
+	if c.VerifyBaseDigest && c.ImageDigest == "" {
+		errs = packersdk.MultiErrorAppend(errs, errVerifyNoDigest)
+	}
+
+	if c.Discard && (c.Commit || c.ExportPath != "") {
 		errs = packersdk.MultiErrorAppend(errs, errArtifactUseConflict)
 	}
 
@@ -182,13 +449,244 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
+	if c.SavePath != "" {
+		if !c.Commit {
+			errs = packersdk.MultiErrorAppend(errs, errSavePathNoCommit)
+		}
+		if fi, err := os.Stat(c.SavePath); err == nil && fi.IsDir() {
+			errs = packersdk.MultiErrorAppend(errs, errSavePathNotFile)
+		}
+	}
+
+	if c.ExportFormat == "" {
+		c.ExportFormat = "tar"
+	}
+
+	switch c.ExportFormat {
+	case "tar", "oci-layout", "squashfs":
+	default:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+			"export_format must be one of tar, oci-layout or squashfs, got %q", c.ExportFormat))
+	}
+
+	if maxLevel, ok := exportCompressionLevels[c.ExportCompression]; !ok {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+			"export_compression must be one of gzip, zstd or xz, got %q", c.ExportCompression))
+	} else if c.ExportCompressionLevel != 0 {
+		if c.ExportCompression == "" {
+			errs = packersdk.MultiErrorAppend(errs, errExportLevelNoCompression)
+		} else if c.ExportCompressionLevel < 1 || c.ExportCompressionLevel > maxLevel {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"export_compression_level for %s must be between 1 and %d", c.ExportCompression, maxLevel))
+		} else if c.ExportFormat == "squashfs" && c.ExportCompression == "xz" {
+			errs = packersdk.MultiErrorAppend(errs, errExportLevelSquashfsXz)
+		}
+	}
+
+	names := make(map[string]bool)
+	for i := range c.Services {
+		if es := c.Services[i].Prepare(); len(es) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, es...)
+		}
+		if names[c.Services[i].Name] {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"service: name %q is used more than once", c.Services[i].Name))
+		}
+		names[c.Services[i].Name] = true
+	}
+
+	for i := range c.Mounts {
+		if es := c.Mounts[i].Prepare(); len(es) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, es...)
+		}
+	}
+
+	secretNames := make(map[string]bool)
+	for i := range c.Secrets {
+		if es := c.Secrets[i].Prepare(); len(es) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, es...)
+		}
+		if secretNames[c.Secrets[i].Name] {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"secret: name %q is used more than once", c.Secrets[i].Name))
+		}
+		secretNames[c.Secrets[i].Name] = true
+	}
+
+	for name, path := range c.CacheMounts {
+		if !nameRegexp.MatchString(name) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"cache_mounts: invalid volume name %q", name))
+		}
+		if !strings.HasPrefix(path, "/") {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"cache_mounts: the path of volume %s must be absolute, got %q", name, path))
+		}
+	}
+	if c.CachePruneAfter < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errCachePruneAfterNegative)
+	}
+	if c.CacheScope == "" && len(c.CacheMounts) > 0 {
+		if wd, err := os.Getwd(); err == nil {
+			c.CacheScope = wd
+		}
+	}
+
+	if c.ForwardSSHAgent && os.Getenv("SSH_AUTH_SOCK") == "" {
+		errs = packersdk.MultiErrorAppend(errs, errNoSSHAgent)
+	}
+
+	for i, instruction := range c.InheritConfig {
+		c.InheritConfig[i] = strings.ToUpper(instruction)
+		if _, ok := inheritableInstructions[c.InheritConfig[i]]; !ok {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"inherit_config: instruction %s can't be inherited", instruction))
+		}
+	}
+
+	if c.StopTimeout == 0 {
+		c.StopTimeout = 10 * time.Second
+	}
+
+	if c.ReadyCommand != "" && c.ReadyHealthcheck {
+		errs = packersdk.MultiErrorAppend(errs, errReadyConflict)
+	}
+	if c.ReadyTimeout < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errReadyTimeoutNegative)
+	}
+	if c.ReadyTimeout == 0 {
+		c.ReadyTimeout = 2 * time.Minute
+	}
+
 	if c.ContainerDir == "" {
 		c.ContainerDir = "/packer-files"
 	}
 
+	// Catch template errors now rather than when the container is started
+	if _, err := c.renderRunCommand(c.runCommandData("/tmp/packer-podman", "packer-podman")); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("run_command: %s", err))
+	}
+
+	if es := c.validate(); len(es) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, es...)
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
-		return nil, errs
+		return warnings, errs
 	}
 
-	return nil, nil
+	return warnings, nil
+}
+
+var digestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// pinImageDigest turns an image reference into one pinned by digest,
+// dropping the tag since podman doesn't accept both at once.
+func pinImageDigest(image, digest string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + "@" + digest
+}
+
+// runCommandData returns the data run_command is rendered with. The
+// temporary directory and the container name are only known once the build
+// runs.
+func (c *Config) runCommandData(tempDir, containerName string) *startContainerTemplate {
+	return &startContainerTemplate{
+		Image:         c.Image,
+		TempDir:       tempDir,
+		ContainerDir:  c.ContainerDir,
+		BuildName:     c.PackerBuildName,
+		ContainerName: containerName,
+		Platform:      c.PullPlatform,
+		RunVars:       c.RunVars,
+	}
+}
+
+// renderRunCommand renders the arguments of run_command with data.
+func (c *Config) renderRunCommand(data *startContainerTemplate) ([]string, error) {
+	ictx := c.ctx
+	ictx.Data = data
+
+	args := make([]string, 0, len(c.RunCommand))
+	for _, v := range c.RunCommand {
+		rendered, err := interpolate.Render(v, &ictx)
+		if err != nil {
+			return nil, err
+		}
+		// Missing map keys, such as misspelled run_vars, don't fail the
+		// rendering but render as <no value>.
+		if strings.Contains(rendered, "<no value>") {
+			return nil, fmt.Errorf("%q refers to an undefined value, such as a run_vars key that isn't set", v)
+		}
+		args = append(args, rendered)
+	}
+	return args, nil
+}
+
+// prepareLogin fills in the login credentials from the password file or the
+// environment, when they aren't set in the template.
+func (c *Config) prepareLogin() []error {
+	if c.LoginPasswordFile != "" {
+		if c.LoginPassword != "" {
+			return []error{errLoginPasswordConflict}
+		}
+
+		password, err := ioutil.ReadFile(c.LoginPasswordFile)
+		if err != nil {
+			return []error{fmt.Errorf("Error reading login_password_file: %s", err)}
+		}
+		c.LoginPassword = strings.TrimRight(string(password), "\r\n")
+	}
+
+	if c.LoginUsername == "" {
+		c.LoginUsername = os.Getenv("PODMAN_LOGIN_USERNAME")
+	}
+	if c.LoginPassword == "" {
+		c.LoginPassword = os.Getenv("PODMAN_LOGIN_PASSWORD")
+	}
+
+	// The password may not come from a sensitive variable anymore, make sure
+	// it doesn't leak into the logs.
+	if c.LoginPassword != "" {
+		packersdk.LogSecretFilter.Set(c.LoginPassword)
+	}
+
+	return nil
+}
+
+// applyPodmanInfo adjusts the defaults that depend on the podman host.
+func (c *Config) applyPodmanInfo(info *PodmanInfo) {
+	// Rootless podman can't pause containers on cgroup v1
+	if c.commitPauseDefaulted && info.Rootless && info.CgroupVersion == "v1" {
+		log.Println("Podman can't pause rootless containers on cgroup v1, won't pause while committing")
+		c.CommitPause = false
+	}
+}
+
+// rootlessWarnings returns warnings for the options that behave differently
+// when podman runs rootless. They are given once podman info tells whether it
+// does, when the build runs.
+func (c *Config) rootlessWarnings() []string {
+	var warnings []string
+	if c.Privileged {
+		warnings = append(warnings, "privileged rootless containers can't gain "+
+			"more privileges than the user running Packer")
+	}
+	if len(c.Device) > 0 {
+		warnings = append(warnings, "rootless containers can only access the "+
+			"devices the user running Packer can access")
+	}
+	hasBindMounts := len(c.Volumes) > 0
+	for _, m := range c.Mounts {
+		if m.Type == "bind" || m.Type == "overlay" {
+			hasBindMounts = true
+		}
+	}
+	if hasBindMounts && c.Userns == "" {
+		warnings = append(warnings, "files in volumes and bind mounts are owned by root inside "+
+			"rootless containers, set userns to keep-id to keep their ownership")
+	}
+	return warnings
 }
@@ -10,87 +10,123 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName           *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType         *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion         *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug               *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce               *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError             *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars            map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars       []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	Type                      *string           `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
-	PauseBeforeConnect        *string           `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
-	SSHHost                   *string           `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
-	SSHPort                   *int              `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
-	SSHUsername               *string           `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
-	SSHPassword               *string           `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
-	SSHKeyPairName            *string           `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
-	SSHTemporaryKeyPairName   *string           `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
-	SSHTemporaryKeyPairType   *string           `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
-	SSHTemporaryKeyPairBits   *int              `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
-	SSHCiphers                []string          `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
-	SSHClearAuthorizedKeys    *bool             `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
-	SSHKEXAlgos               []string          `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
-	SSHPrivateKeyFile         *string           `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
-	SSHCertificateFile        *string           `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
-	SSHPty                    *bool             `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
-	SSHTimeout                *string           `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
-	SSHWaitTimeout            *string           `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
-	SSHAgentAuth              *bool             `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
-	SSHDisableAgentForwarding *bool             `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
-	SSHHandshakeAttempts      *int              `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
-	SSHBastionHost            *string           `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
-	SSHBastionPort            *int              `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
-	SSHBastionAgentAuth       *bool             `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
-	SSHBastionUsername        *string           `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
-	SSHBastionPassword        *string           `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
-	SSHBastionInteractive     *bool             `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
-	SSHBastionPrivateKeyFile  *string           `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
-	SSHBastionCertificateFile *string           `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
-	SSHFileTransferMethod     *string           `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
-	SSHProxyHost              *string           `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
-	SSHProxyPort              *int              `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
-	SSHProxyUsername          *string           `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
-	SSHProxyPassword          *string           `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
-	SSHKeepAliveInterval      *string           `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
-	SSHReadWriteTimeout       *string           `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
-	SSHRemoteTunnels          []string          `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
-	SSHLocalTunnels           []string          `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
-	SSHPublicKey              []byte            `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
-	SSHPrivateKey             []byte            `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
-	WinRMUser                 *string           `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
-	WinRMPassword             *string           `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
-	WinRMHost                 *string           `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
-	WinRMNoProxy              *bool             `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
-	WinRMPort                 *int              `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
-	WinRMTimeout              *string           `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
-	WinRMUseSSL               *bool             `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
-	WinRMInsecure             *bool             `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
-	WinRMUseNTLM              *bool             `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
-	Author                    *string           `mapstructure:"author" cty:"author" hcl:"author"`
-	Changes                   []string          `mapstructure:"changes" cty:"changes" hcl:"changes"`
-	Commit                    *bool             `mapstructure:"commit" required:"true" cty:"commit" hcl:"commit"`
-	ContainerDir              *string           `mapstructure:"container_dir" required:"false" cty:"container_dir" hcl:"container_dir"`
-	Device                    []string          `mapstructure:"device" required:"false" cty:"device" hcl:"device"`
-	Discard                   *bool             `mapstructure:"discard" required:"true" cty:"discard" hcl:"discard"`
-	CapAdd                    []string          `mapstructure:"cap_add" required:"false" cty:"cap_add" hcl:"cap_add"`
-	CapDrop                   []string          `mapstructure:"cap_drop" required:"false" cty:"cap_drop" hcl:"cap_drop"`
-	ExecUser                  *string           `mapstructure:"exec_user" required:"false" cty:"exec_user" hcl:"exec_user"`
-	ExportPath                *string           `mapstructure:"export_path" required:"true" cty:"export_path" hcl:"export_path"`
-	Image                     *string           `mapstructure:"image" required:"true" cty:"image" hcl:"image"`
-	Message                   *string           `mapstructure:"message" required:"true" cty:"message" hcl:"message"`
-	Privileged                *bool             `mapstructure:"privileged" required:"false" cty:"privileged" hcl:"privileged"`
-	Pty                       *bool             `cty:"pty" hcl:"pty"`
-	Pull                      *bool             `mapstructure:"pull" required:"false" cty:"pull" hcl:"pull"`
-	RunCommand                []string          `mapstructure:"run_command" required:"false" cty:"run_command" hcl:"run_command"`
-	TmpFs                     []string          `mapstructure:"tmpfs" required:"false" cty:"tmpfs" hcl:"tmpfs"`
-	Volumes                   map[string]string `mapstructure:"volumes" required:"false" cty:"volumes" hcl:"volumes"`
-	FixUploadOwner            *bool             `mapstructure:"fix_upload_owner" required:"false" cty:"fix_upload_owner" hcl:"fix_upload_owner"`
-	Systemd                   *string           `mapstructure:"systemd" required:"false" cty:"systemd" hcl:"systemd"`
-	Login                     *bool             `mapstructure:"login" required:"false" cty:"login" hcl:"login"`
-	LoginPassword             *string           `mapstructure:"login_password" required:"false" cty:"login_password" hcl:"login_password"`
-	LoginServer               *string           `mapstructure:"login_server" required:"false" cty:"login_server" hcl:"login_server"`
-	LoginUsername             *string           `mapstructure:"login_username" required:"false" cty:"login_username" hcl:"login_username"`
+	PackerBuildName           *string             `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType         *string             `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion         *string             `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug               *bool               `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce               *bool               `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError             *string             `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars            map[string]string   `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars       []string            `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	Type                      *string             `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
+	PauseBeforeConnect        *string             `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
+	SSHHost                   *string             `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
+	SSHPort                   *int                `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
+	SSHUsername               *string             `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
+	SSHPassword               *string             `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
+	SSHKeyPairName            *string             `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
+	SSHTemporaryKeyPairName   *string             `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
+	SSHTemporaryKeyPairType   *string             `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
+	SSHTemporaryKeyPairBits   *int                `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
+	SSHCiphers                []string            `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
+	SSHClearAuthorizedKeys    *bool               `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
+	SSHKEXAlgos               []string            `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
+	SSHPrivateKeyFile         *string             `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
+	SSHCertificateFile        *string             `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
+	SSHPty                    *bool               `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
+	SSHTimeout                *string             `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
+	SSHWaitTimeout            *string             `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
+	SSHAgentAuth              *bool               `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
+	SSHDisableAgentForwarding *bool               `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
+	SSHHandshakeAttempts      *int                `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
+	SSHBastionHost            *string             `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
+	SSHBastionPort            *int                `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
+	SSHBastionAgentAuth       *bool               `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
+	SSHBastionUsername        *string             `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
+	SSHBastionPassword        *string             `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
+	SSHBastionInteractive     *bool               `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
+	SSHBastionPrivateKeyFile  *string             `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
+	SSHBastionCertificateFile *string             `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
+	SSHFileTransferMethod     *string             `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
+	SSHProxyHost              *string             `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
+	SSHProxyPort              *int                `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
+	SSHProxyUsername          *string             `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
+	SSHProxyPassword          *string             `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
+	SSHKeepAliveInterval      *string             `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
+	SSHReadWriteTimeout       *string             `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
+	SSHRemoteTunnels          []string            `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
+	SSHLocalTunnels           []string            `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
+	SSHPublicKey              []byte              `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
+	SSHPrivateKey             []byte              `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
+	WinRMUser                 *string             `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
+	WinRMPassword             *string             `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
+	WinRMHost                 *string             `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
+	WinRMNoProxy              *bool               `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
+	WinRMPort                 *int                `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
+	WinRMTimeout              *string             `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
+	WinRMUseSSL               *bool               `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
+	WinRMInsecure             *bool               `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
+	WinRMUseNTLM              *bool               `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
+	Author                    *string             `mapstructure:"author" cty:"author" hcl:"author"`
+	Changes                   []string            `mapstructure:"changes" cty:"changes" hcl:"changes"`
+	InheritConfig             []string            `mapstructure:"inherit_config" required:"false" cty:"inherit_config" hcl:"inherit_config"`
+	Commit                    *bool               `mapstructure:"commit" required:"true" cty:"commit" hcl:"commit"`
+	CommitPause               *bool               `mapstructure:"commit_pause" required:"false" cty:"commit_pause" hcl:"commit_pause"`
+	ContainerDir              *string             `mapstructure:"container_dir" required:"false" cty:"container_dir" hcl:"container_dir"`
+	Device                    []string            `mapstructure:"device" required:"false" cty:"device" hcl:"device"`
+	Discard                   *bool               `mapstructure:"discard" required:"true" cty:"discard" hcl:"discard"`
+	CapAdd                    []string            `mapstructure:"cap_add" required:"false" cty:"cap_add" hcl:"cap_add"`
+	CapDrop                   []string            `mapstructure:"cap_drop" required:"false" cty:"cap_drop" hcl:"cap_drop"`
+	ExecUser                  *string             `mapstructure:"exec_user" required:"false" cty:"exec_user" hcl:"exec_user"`
+	ExportPath                *string             `mapstructure:"export_path" required:"true" cty:"export_path" hcl:"export_path"`
+	ExportFormat              *string             `mapstructure:"export_format" required:"false" cty:"export_format" hcl:"export_format"`
+	ExportCompression         *string             `mapstructure:"export_compression" required:"false" cty:"export_compression" hcl:"export_compression"`
+	ExportCompressionLevel    *int                `mapstructure:"export_compression_level" required:"false" cty:"export_compression_level" hcl:"export_compression_level"`
+	Image                     *string             `mapstructure:"image" required:"true" cty:"image" hcl:"image"`
+	ImageDigest               *string             `mapstructure:"image_digest" required:"false" cty:"image_digest" hcl:"image_digest"`
+	VerifyBaseDigest          *bool               `mapstructure:"verify_base_digest" required:"false" cty:"verify_base_digest" hcl:"verify_base_digest"`
+	Message                   *string             `mapstructure:"message" required:"true" cty:"message" hcl:"message"`
+	StopBeforeCommit          *bool               `mapstructure:"stop_before_commit" required:"false" cty:"stop_before_commit" hcl:"stop_before_commit"`
+	SavePath                  *string             `mapstructure:"save_path" required:"false" cty:"save_path" hcl:"save_path"`
+	Privileged                *bool               `mapstructure:"privileged" required:"false" cty:"privileged" hcl:"privileged"`
+	Pty                       *bool               `cty:"pty" hcl:"pty"`
+	Pull                      *bool               `mapstructure:"pull" required:"false" cty:"pull" hcl:"pull"`
+	PullPolicy                *string             `mapstructure:"pull_policy" required:"false" cty:"pull_policy" hcl:"pull_policy"`
+	PullPlatform              *string             `mapstructure:"pull_platform" required:"false" cty:"pull_platform" hcl:"pull_platform"`
+	TLSVerify                 *bool               `mapstructure:"tls_verify" required:"false" cty:"tls_verify" hcl:"tls_verify"`
+	CertDir                   *string             `mapstructure:"cert_dir" required:"false" cty:"cert_dir" hcl:"cert_dir"`
+	AuthFile                  *string             `mapstructure:"authfile" required:"false" cty:"authfile" hcl:"authfile"`
+	RegistryAuth              []FlatRegistryAuth  `mapstructure:"registry_auth" required:"false" cty:"registry_auth" hcl:"registry_auth"`
+	PullRetries               *int                `mapstructure:"pull_retries" required:"false" cty:"pull_retries" hcl:"pull_retries"`
+	PullRetryDelay            *string             `mapstructure:"pull_retry_delay" required:"false" cty:"pull_retry_delay" hcl:"pull_retry_delay"`
+	DecryptionKey             *string             `mapstructure:"decryption_key" required:"false" cty:"decryption_key" hcl:"decryption_key"`
+	RunCommand                []string            `mapstructure:"run_command" required:"false" cty:"run_command" hcl:"run_command"`
+	RunVars                   map[string]string   `mapstructure:"run_vars" required:"false" cty:"run_vars" hcl:"run_vars"`
+	TmpFs                     []string            `mapstructure:"tmpfs" required:"false" cty:"tmpfs" hcl:"tmpfs"`
+	Volumes                   map[string]string   `mapstructure:"volumes" required:"false" cty:"volumes" hcl:"volumes"`
+	Mounts                    []FlatMountConfig   `mapstructure:"mount" required:"false" cty:"mount" hcl:"mount"`
+	Userns                    *string             `mapstructure:"userns" required:"false" cty:"userns" hcl:"userns"`
+	FixUploadOwner            *bool               `mapstructure:"fix_upload_owner" required:"false" cty:"fix_upload_owner" hcl:"fix_upload_owner"`
+	KeepContainerOnError      *bool               `mapstructure:"keep_container_on_error" required:"false" cty:"keep_container_on_error" hcl:"keep_container_on_error"`
+	StopSignal                *string             `mapstructure:"stop_signal" required:"false" cty:"stop_signal" hcl:"stop_signal"`
+	StopTimeout               *string             `mapstructure:"stop_timeout" required:"false" cty:"stop_timeout" hcl:"stop_timeout"`
+	Services                  []FlatServiceConfig `mapstructure:"service" required:"false" cty:"service" hcl:"service"`
+	Secrets                   []FlatSecretConfig  `mapstructure:"secret" required:"false" cty:"secret" hcl:"secret"`
+	CacheMounts               map[string]string   `mapstructure:"cache_mounts" required:"false" cty:"cache_mounts" hcl:"cache_mounts"`
+	CachePruneAfter           *string             `mapstructure:"cache_prune_after" required:"false" cty:"cache_prune_after" hcl:"cache_prune_after"`
+	CacheScope                *string             `mapstructure:"cache_scope" required:"false" cty:"cache_scope" hcl:"cache_scope"`
+	ForwardSSHAgent           *bool               `mapstructure:"forward_ssh_agent" required:"false" cty:"forward_ssh_agent" hcl:"forward_ssh_agent"`
+	ForwardProxyEnv           *bool               `mapstructure:"forward_proxy_env" required:"false" cty:"forward_proxy_env" hcl:"forward_proxy_env"`
+	SkipPreflight             *bool               `mapstructure:"skip_preflight" required:"false" cty:"skip_preflight" hcl:"skip_preflight"`
+	Systemd                   *string             `mapstructure:"systemd" required:"false" cty:"systemd" hcl:"systemd"`
+	ReadyCommand              *string             `mapstructure:"ready_command" required:"false" cty:"ready_command" hcl:"ready_command"`
+	ReadyHealthcheck          *bool               `mapstructure:"ready_healthcheck" required:"false" cty:"ready_healthcheck" hcl:"ready_healthcheck"`
+	ReadyTimeout              *string             `mapstructure:"ready_timeout" required:"false" cty:"ready_timeout" hcl:"ready_timeout"`
+	Login                     *bool               `mapstructure:"login" required:"false" cty:"login" hcl:"login"`
+	LoginPassword             *string             `mapstructure:"login_password" required:"false" cty:"login_password" hcl:"login_password"`
+	LoginPasswordFile         *string             `mapstructure:"login_password_file" required:"false" cty:"login_password_file" hcl:"login_password_file"`
+	LoginServer               *string             `mapstructure:"login_server" required:"false" cty:"login_server" hcl:"login_server"`
+	LoginUsername             *string             `mapstructure:"login_username" required:"false" cty:"login_username" hcl:"login_username"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -164,7 +200,9 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"winrm_use_ntlm":               &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
 		"author":                       &hcldec.AttrSpec{Name: "author", Type: cty.String, Required: false},
 		"changes":                      &hcldec.AttrSpec{Name: "changes", Type: cty.List(cty.String), Required: false},
+		"inherit_config":               &hcldec.AttrSpec{Name: "inherit_config", Type: cty.List(cty.String), Required: false},
 		"commit":                       &hcldec.AttrSpec{Name: "commit", Type: cty.Bool, Required: false},
+		"commit_pause":                 &hcldec.AttrSpec{Name: "commit_pause", Type: cty.Bool, Required: false},
 		"container_dir":                &hcldec.AttrSpec{Name: "container_dir", Type: cty.String, Required: false},
 		"device":                       &hcldec.AttrSpec{Name: "device", Type: cty.List(cty.String), Required: false},
 		"discard":                      &hcldec.AttrSpec{Name: "discard", Type: cty.Bool, Required: false},
@@ -172,20 +210,176 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"cap_drop":                     &hcldec.AttrSpec{Name: "cap_drop", Type: cty.List(cty.String), Required: false},
 		"exec_user":                    &hcldec.AttrSpec{Name: "exec_user", Type: cty.String, Required: false},
 		"export_path":                  &hcldec.AttrSpec{Name: "export_path", Type: cty.String, Required: false},
+		"export_format":                &hcldec.AttrSpec{Name: "export_format", Type: cty.String, Required: false},
+		"export_compression":           &hcldec.AttrSpec{Name: "export_compression", Type: cty.String, Required: false},
+		"export_compression_level":     &hcldec.AttrSpec{Name: "export_compression_level", Type: cty.Number, Required: false},
 		"image":                        &hcldec.AttrSpec{Name: "image", Type: cty.String, Required: false},
+		"image_digest":                 &hcldec.AttrSpec{Name: "image_digest", Type: cty.String, Required: false},
+		"verify_base_digest":           &hcldec.AttrSpec{Name: "verify_base_digest", Type: cty.Bool, Required: false},
 		"message":                      &hcldec.AttrSpec{Name: "message", Type: cty.String, Required: false},
+		"stop_before_commit":           &hcldec.AttrSpec{Name: "stop_before_commit", Type: cty.Bool, Required: false},
+		"save_path":                    &hcldec.AttrSpec{Name: "save_path", Type: cty.String, Required: false},
 		"privileged":                   &hcldec.AttrSpec{Name: "privileged", Type: cty.Bool, Required: false},
 		"pty":                          &hcldec.AttrSpec{Name: "pty", Type: cty.Bool, Required: false},
 		"pull":                         &hcldec.AttrSpec{Name: "pull", Type: cty.Bool, Required: false},
+		"pull_policy":                  &hcldec.AttrSpec{Name: "pull_policy", Type: cty.String, Required: false},
+		"pull_platform":                &hcldec.AttrSpec{Name: "pull_platform", Type: cty.String, Required: false},
+		"tls_verify":                   &hcldec.AttrSpec{Name: "tls_verify", Type: cty.Bool, Required: false},
+		"cert_dir":                     &hcldec.AttrSpec{Name: "cert_dir", Type: cty.String, Required: false},
+		"authfile":                     &hcldec.AttrSpec{Name: "authfile", Type: cty.String, Required: false},
+		"registry_auth":                &hcldec.BlockListSpec{TypeName: "registry_auth", Nested: hcldec.ObjectSpec((*FlatRegistryAuth)(nil).HCL2Spec())},
+		"pull_retries":                 &hcldec.AttrSpec{Name: "pull_retries", Type: cty.Number, Required: false},
+		"pull_retry_delay":             &hcldec.AttrSpec{Name: "pull_retry_delay", Type: cty.String, Required: false},
+		"decryption_key":               &hcldec.AttrSpec{Name: "decryption_key", Type: cty.String, Required: false},
 		"run_command":                  &hcldec.AttrSpec{Name: "run_command", Type: cty.List(cty.String), Required: false},
+		"run_vars":                     &hcldec.AttrSpec{Name: "run_vars", Type: cty.Map(cty.String), Required: false},
 		"tmpfs":                        &hcldec.AttrSpec{Name: "tmpfs", Type: cty.List(cty.String), Required: false},
 		"volumes":                      &hcldec.AttrSpec{Name: "volumes", Type: cty.Map(cty.String), Required: false},
+		"mount":                        &hcldec.BlockListSpec{TypeName: "mount", Nested: hcldec.ObjectSpec((*FlatMountConfig)(nil).HCL2Spec())},
+		"userns":                       &hcldec.AttrSpec{Name: "userns", Type: cty.String, Required: false},
 		"fix_upload_owner":             &hcldec.AttrSpec{Name: "fix_upload_owner", Type: cty.Bool, Required: false},
+		"keep_container_on_error":      &hcldec.AttrSpec{Name: "keep_container_on_error", Type: cty.Bool, Required: false},
+		"stop_signal":                  &hcldec.AttrSpec{Name: "stop_signal", Type: cty.String, Required: false},
+		"stop_timeout":                 &hcldec.AttrSpec{Name: "stop_timeout", Type: cty.String, Required: false},
+		"service":                      &hcldec.BlockListSpec{TypeName: "service", Nested: hcldec.ObjectSpec((*FlatServiceConfig)(nil).HCL2Spec())},
+		"secret":                       &hcldec.BlockListSpec{TypeName: "secret", Nested: hcldec.ObjectSpec((*FlatSecretConfig)(nil).HCL2Spec())},
+		"cache_mounts":                 &hcldec.AttrSpec{Name: "cache_mounts", Type: cty.Map(cty.String), Required: false},
+		"cache_prune_after":            &hcldec.AttrSpec{Name: "cache_prune_after", Type: cty.String, Required: false},
+		"cache_scope":                  &hcldec.AttrSpec{Name: "cache_scope", Type: cty.String, Required: false},
+		"forward_ssh_agent":            &hcldec.AttrSpec{Name: "forward_ssh_agent", Type: cty.Bool, Required: false},
+		"forward_proxy_env":            &hcldec.AttrSpec{Name: "forward_proxy_env", Type: cty.Bool, Required: false},
+		"skip_preflight":               &hcldec.AttrSpec{Name: "skip_preflight", Type: cty.Bool, Required: false},
 		"systemd":                      &hcldec.AttrSpec{Name: "systemd", Type: cty.String, Required: false},
+		"ready_command":                &hcldec.AttrSpec{Name: "ready_command", Type: cty.String, Required: false},
+		"ready_healthcheck":            &hcldec.AttrSpec{Name: "ready_healthcheck", Type: cty.Bool, Required: false},
+		"ready_timeout":                &hcldec.AttrSpec{Name: "ready_timeout", Type: cty.String, Required: false},
 		"login":                        &hcldec.AttrSpec{Name: "login", Type: cty.Bool, Required: false},
 		"login_password":               &hcldec.AttrSpec{Name: "login_password", Type: cty.String, Required: false},
+		"login_password_file":          &hcldec.AttrSpec{Name: "login_password_file", Type: cty.String, Required: false},
 		"login_server":                 &hcldec.AttrSpec{Name: "login_server", Type: cty.String, Required: false},
 		"login_username":               &hcldec.AttrSpec{Name: "login_username", Type: cty.String, Required: false},
 	}
 	return s
 }
+
+// FlatMountConfig is an auto-generated flat version of MountConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatMountConfig struct {
+	Type    *string  `mapstructure:"type" required:"true" cty:"type" hcl:"type"`
+	Source  *string  `mapstructure:"source" required:"false" cty:"source" hcl:"source"`
+	Target  *string  `mapstructure:"target" required:"true" cty:"target" hcl:"target"`
+	Options []string `mapstructure:"options" required:"false" cty:"options" hcl:"options"`
+}
+
+// FlatMapstructure returns a new FlatMountConfig.
+// FlatMountConfig is an auto-generated flat version of MountConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*MountConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatMountConfig)
+}
+
+// HCL2Spec returns the hcl spec of a MountConfig.
+// This spec is used by HCL to read the fields of MountConfig.
+// The decoded values from this spec will then be applied to a FlatMountConfig.
+func (*FlatMountConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"type":    &hcldec.AttrSpec{Name: "type", Type: cty.String, Required: false},
+		"source":  &hcldec.AttrSpec{Name: "source", Type: cty.String, Required: false},
+		"target":  &hcldec.AttrSpec{Name: "target", Type: cty.String, Required: false},
+		"options": &hcldec.AttrSpec{Name: "options", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
+
+// FlatRegistryAuth is an auto-generated flat version of RegistryAuth.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatRegistryAuth struct {
+	Server   *string  `mapstructure:"server" required:"true" cty:"server" hcl:"server"`
+	Username *string  `mapstructure:"username" required:"false" cty:"username" hcl:"username"`
+	Password *string  `mapstructure:"password" required:"false" cty:"password" hcl:"password"`
+	Command  []string `mapstructure:"command" required:"false" cty:"command" hcl:"command"`
+}
+
+// FlatMapstructure returns a new FlatRegistryAuth.
+// FlatRegistryAuth is an auto-generated flat version of RegistryAuth.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*RegistryAuth) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatRegistryAuth)
+}
+
+// HCL2Spec returns the hcl spec of a RegistryAuth.
+// This spec is used by HCL to read the fields of RegistryAuth.
+// The decoded values from this spec will then be applied to a FlatRegistryAuth.
+func (*FlatRegistryAuth) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"server":   &hcldec.AttrSpec{Name: "server", Type: cty.String, Required: false},
+		"username": &hcldec.AttrSpec{Name: "username", Type: cty.String, Required: false},
+		"password": &hcldec.AttrSpec{Name: "password", Type: cty.String, Required: false},
+		"command":  &hcldec.AttrSpec{Name: "command", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
+
+// FlatSecretConfig is an auto-generated flat version of SecretConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatSecretConfig struct {
+	Name *string `mapstructure:"name" required:"true" cty:"name" hcl:"name"`
+	File *string `mapstructure:"file" required:"false" cty:"file" hcl:"file"`
+	Env  *string `mapstructure:"env" required:"false" cty:"env" hcl:"env"`
+}
+
+// FlatMapstructure returns a new FlatSecretConfig.
+// FlatSecretConfig is an auto-generated flat version of SecretConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*SecretConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatSecretConfig)
+}
+
+// HCL2Spec returns the hcl spec of a SecretConfig.
+// This spec is used by HCL to read the fields of SecretConfig.
+// The decoded values from this spec will then be applied to a FlatSecretConfig.
+func (*FlatSecretConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name": &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"file": &hcldec.AttrSpec{Name: "file", Type: cty.String, Required: false},
+		"env":  &hcldec.AttrSpec{Name: "env", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatServiceConfig is an auto-generated flat version of ServiceConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatServiceConfig struct {
+	Name           *string           `mapstructure:"name" required:"true" cty:"name" hcl:"name"`
+	Image          *string           `mapstructure:"image" required:"true" cty:"image" hcl:"image"`
+	Env            map[string]string `mapstructure:"env" required:"false" cty:"env" hcl:"env"`
+	Command        []string          `mapstructure:"command" required:"false" cty:"command" hcl:"command"`
+	Ports          []string          `mapstructure:"ports" required:"false" cty:"ports" hcl:"ports"`
+	HealthCommand  *string           `mapstructure:"health_command" required:"false" cty:"health_command" hcl:"health_command"`
+	HealthInterval *string           `mapstructure:"health_interval" required:"false" cty:"health_interval" hcl:"health_interval"`
+	HealthTimeout  *string           `mapstructure:"health_timeout" required:"false" cty:"health_timeout" hcl:"health_timeout"`
+}
+
+// FlatMapstructure returns a new FlatServiceConfig.
+// FlatServiceConfig is an auto-generated flat version of ServiceConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*ServiceConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatServiceConfig)
+}
+
+// HCL2Spec returns the hcl spec of a ServiceConfig.
+// This spec is used by HCL to read the fields of ServiceConfig.
+// The decoded values from this spec will then be applied to a FlatServiceConfig.
+func (*FlatServiceConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":            &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"image":           &hcldec.AttrSpec{Name: "image", Type: cty.String, Required: false},
+		"env":             &hcldec.AttrSpec{Name: "env", Type: cty.Map(cty.String), Required: false},
+		"command":         &hcldec.AttrSpec{Name: "command", Type: cty.List(cty.String), Required: false},
+		"ports":           &hcldec.AttrSpec{Name: "ports", Type: cty.List(cty.String), Required: false},
+		"health_command":  &hcldec.AttrSpec{Name: "health_command", Type: cty.String, Required: false},
+		"health_interval": &hcldec.AttrSpec{Name: "health_interval", Type: cty.String, Required: false},
+		"health_timeout":  &hcldec.AttrSpec{Name: "health_timeout", Type: cty.String, Required: false},
+	}
+	return s
+}
@@ -3,7 +3,9 @@ package podman
 import (
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func testConfig() map[string]interface{} {
@@ -79,10 +81,16 @@ func TestConfigPrepare_exportPathAndCommit(t *testing.T) {
 	warns, errs := (&Config{}).Prepare(raw)
 	testConfigOk(t, warns, errs)
 
-	// Commit AND export specified (invalid)
+	// Commit AND export specified
 	raw["commit"] = true
 	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// Commit, export AND discard (invalid)
+	raw["discard"] = true
+	warns, errs = (&Config{}).Prepare(raw)
 	testConfigErr(t, warns, errs)
+	delete(raw, "discard")
 
 	// Commit but no export
 	delete(raw, "export_path")
@@ -90,6 +98,31 @@ func TestConfigPrepare_exportPathAndCommit(t *testing.T) {
 	testConfigOk(t, warns, errs)
 }
 
+func TestConfigPrepare_savePath(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	raw := testConfig()
+
+	// Save path without commit (invalid)
+	raw["save_path"] = "image.tar"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Save path with commit
+	raw["commit"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// Bad save path (directory)
+	raw["save_path"] = td
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
 func TestConfigPrepare_exportDiscard(t *testing.T) {
 	raw := testConfig()
 
@@ -133,15 +166,666 @@ func TestConfigPrepare_pull(t *testing.T) {
 	var c Config
 	warns, errs := c.Prepare(raw)
 	testConfigOk(t, warns, errs)
-	if !c.Pull {
+	if c.PullPolicy != "always" {
 		t.Fatal("should pull by default")
 	}
 
-	// Pull set
+	// Deprecated pull set
 	raw["pull"] = false
+	c = Config{}
 	warns, errs = c.Prepare(raw)
-	testConfigOk(t, warns, errs)
-	if c.Pull {
+	if len(warns) == 0 {
+		t.Fatal("should warn about pull")
+	}
+	if errs != nil {
+		t.Fatalf("bad: %s", errs)
+	}
+	if c.PullPolicy != "never" {
 		t.Fatal("should not pull")
 	}
+
+	// Both pull and pull_policy set
+	raw["pull_policy"] = "missing"
+	warns, errs = (&Config{}).Prepare(raw)
+	if errs == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestConfigPrepare_services(t *testing.T) {
+	raw := testConfig()
+	raw["service"] = []map[string]interface{}{
+		{"name": "db", "image": "postgres", "health_command": "pg_isready"},
+	}
+
+	// Good service, with defaults
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if len(c.Services) != 1 {
+		t.Fatalf("bad: %#v", c.Services)
+	}
+	if c.Services[0].HealthInterval != time.Second || c.Services[0].HealthTimeout != time.Minute {
+		t.Fatalf("bad: %#v", c.Services[0])
+	}
+
+	// Duplicate names
+	raw["service"] = []map[string]interface{}{
+		{"name": "db", "image": "postgres"},
+		{"name": "db", "image": "mariadb"},
+	}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Bad name and missing image
+	for _, service := range []map[string]interface{}{
+		{"name": "my db", "image": "postgres"},
+		{"name": "db"},
+	} {
+		raw["service"] = []map[string]interface{}{service}
+		warns, errs = (&Config{}).Prepare(raw)
+		testConfigErr(t, warns, errs)
+	}
+}
+
+func TestConfigPrepare_secrets(t *testing.T) {
+	defer os.Setenv("PACKER_TEST_SECRET", os.Getenv("PACKER_TEST_SECRET"))
+	os.Setenv("PACKER_TEST_SECRET", "s3cr3t-token")
+
+	raw := testConfig()
+	raw["secret"] = []map[string]interface{}{
+		{"name": "token", "env": "PACKER_TEST_SECRET"},
+	}
+
+	// Good secret, read from the environment
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if len(c.Secrets) != 1 || string(c.Secrets[0].value) != "s3cr3t-token" {
+		t.Fatalf("bad: %#v", c.Secrets)
+	}
+
+	// Duplicate names
+	raw["secret"] = []map[string]interface{}{
+		{"name": "token", "env": "PACKER_TEST_SECRET"},
+		{"name": "token", "env": "PACKER_TEST_SECRET"},
+	}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Bad name, no source, both sources, unset variable and missing file
+	for _, secret := range []map[string]interface{}{
+		{"name": "../token", "env": "PACKER_TEST_SECRET"},
+		{"name": "token"},
+		{"name": "token", "env": "PACKER_TEST_SECRET", "file": "token.txt"},
+		{"name": "token", "env": "PACKER_TEST_SECRET_UNSET"},
+		{"name": "token", "file": "test-fixtures/missing"},
+	} {
+		raw["secret"] = []map[string]interface{}{secret}
+		warns, errs = (&Config{}).Prepare(raw)
+		testConfigErr(t, warns, errs)
+	}
+}
+
+func TestConfigPrepare_cacheMounts(t *testing.T) {
+	raw := testConfig()
+	raw["cache_mounts"] = map[string]string{"dnf-cache": "/var/cache/dnf"}
+	raw["cache_prune_after"] = "168h"
+
+	// Good
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.CachePruneAfter != 168*time.Hour {
+		t.Fatalf("bad: %s", c.CachePruneAfter)
+	}
+	wd, _ := os.Getwd()
+	if c.CacheScope != wd {
+		t.Fatalf("bad: %s", c.CacheScope)
+	}
+
+	// Explicit scope
+	raw["cache_scope"] = "/templates/example"
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.CacheScope != "/templates/example" {
+		t.Fatalf("bad: %s", c.CacheScope)
+	}
+	delete(raw, "cache_scope")
+
+	// Bad volume name
+	raw["cache_mounts"] = map[string]string{"/var/cache": "/var/cache/dnf"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Relative path
+	raw["cache_mounts"] = map[string]string{"dnf-cache": "var/cache/dnf"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Negative prune delay
+	raw["cache_mounts"] = map[string]string{"dnf-cache": "/var/cache/dnf"}
+	raw["cache_prune_after"] = "-1h"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_forwardSSHAgent(t *testing.T) {
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+
+	raw := testConfig()
+	raw["forward_ssh_agent"] = true
+
+	// Good, with an agent
+	os.Setenv("SSH_AUTH_SOCK", "/tmp/ssh-agent/agent.1234")
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// Bad, without an agent
+	os.Setenv("SSH_AUTH_SOCK", "")
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_ready(t *testing.T) {
+	raw := testConfig()
+
+	// Default timeout
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.ReadyTimeout != 2*time.Minute {
+		t.Fatalf("bad: %s", c.ReadyTimeout)
+	}
+
+	// ready_command and ready_healthcheck conflict
+	raw["ready_command"] = "test -S /run/app.sock"
+	raw["ready_healthcheck"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Negative timeout
+	delete(raw, "ready_healthcheck")
+	raw["ready_timeout"] = "-5s"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_systemd(t *testing.T) {
+	raw := testConfig()
+
+	for _, value := range []string{"true", "false", "always"} {
+		raw["systemd"] = value
+		warns, errs := (&Config{}).Prepare(raw)
+		testConfigOk(t, warns, errs)
+	}
+
+	raw["systemd"] = "yes"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_runCommand(t *testing.T) {
+	raw := testConfig()
+	raw["packer_build_name"] = "example"
+	raw["pull_platform"] = "linux/arm64"
+	raw["run_vars"] = map[string]string{"memory": "1g"}
+	raw["run_command"] = []string{
+		"-d", "--name", "{{.ContainerName}}", "--memory={{.RunVars.memory}}",
+		"--label=build={{.BuildName}}", "--platform={{.Platform}}",
+		"-v", "{{.TempDir}}/cache:{{.ContainerDir}}/cache", "--", "{{.Image}}",
+	}
+
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	args, err := c.renderRunCommand(c.runCommandData("/tmp/packer-123", "packer-abc"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := "-d --name packer-abc --memory=1g --label=build=example --platform=linux/arm64 " +
+		"-v /tmp/packer-123/cache:/packer-files/cache -- bar"
+	if strings.Join(args, " ") != expected {
+		t.Fatalf("bad: %s", strings.Join(args, " "))
+	}
+
+	// Template errors are caught before the build
+	raw["run_command"] = []string{"-d", "--", "{{.Nope}}"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	raw["run_command"] = []string{"-d", "--", "{{.Image"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Misspelled run_vars too
+	raw["run_command"] = []string{"-d", "--memory={{.RunVars.memroy}}", "--", "{{.Image}}"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+	if !strings.Contains(errs.Error(), "run_vars") {
+		t.Fatalf("bad: %s", errs)
+	}
+}
+
+func TestConfigPrepare_mounts(t *testing.T) {
+	raw := testConfig()
+	raw["mount"] = []map[string]interface{}{
+		{"type": "bind", "source": "/src", "target": "/src", "options": []string{"ro", "Z"}},
+		{"type": "bind", "source": "/src", "target": "/mirror"},
+		{"type": "tmpfs", "target": "/scratch", "options": []string{"tmpfs-size=64m"}},
+	}
+
+	// Good, the same source can be mounted twice
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if len(c.Mounts) != 3 || c.Mounts[0].Options[1] != "Z" {
+		t.Fatalf("bad: %#v", c.Mounts)
+	}
+
+	// Bad type, target, source and options
+	for _, mount := range []map[string]interface{}{
+		{"type": "nfs", "source": "/src", "target": "/src"},
+		{"type": "bind", "source": "/src", "target": "src"},
+		{"type": "bind", "source": "src", "target": "/src"},
+		{"type": "volume", "target": "/src"},
+		{"type": "tmpfs", "source": "/src", "target": "/src"},
+		{"type": "bind", "source": "/src", "target": "/src", "options": []string{"ro,U"}},
+	} {
+		raw["mount"] = []map[string]interface{}{mount}
+		warns, errs = (&Config{}).Prepare(raw)
+		testConfigErr(t, warns, errs)
+	}
+}
+
+func TestConfigPrepare_inheritConfig(t *testing.T) {
+	raw := testConfig()
+
+	// Defaults to CMD and ENTRYPOINT
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if strings.Join(c.InheritConfig, " ") != "CMD ENTRYPOINT" {
+		t.Fatalf("bad: %#v", c.InheritConfig)
+	}
+
+	// Can be turned off
+	raw["inherit_config"] = []string{}
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if len(c.InheritConfig) != 0 {
+		t.Fatalf("bad: %#v", c.InheritConfig)
+	}
+
+	// Case insensitive
+	raw["inherit_config"] = []string{"env", "Workdir"}
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if strings.Join(c.InheritConfig, " ") != "ENV WORKDIR" {
+		t.Fatalf("bad: %#v", c.InheritConfig)
+	}
+
+	// Unknown instruction
+	raw["inherit_config"] = []string{"RUN"}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_pullPolicy(t *testing.T) {
+	raw := testConfig()
+
+	// Good policies
+	for _, policy := range []string{"always", "missing", "never", "newer"} {
+		raw["pull_policy"] = policy
+		var c Config
+		warns, errs := c.Prepare(raw)
+		testConfigOk(t, warns, errs)
+		if c.PullPolicy != policy {
+			t.Fatalf("bad: %s", c.PullPolicy)
+		}
+	}
+
+	// Bad policy
+	raw["pull_policy"] = "sometimes"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_pullRetries(t *testing.T) {
+	raw := testConfig()
+
+	// No retries, the delay still gets a default
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.PullRetries != 0 {
+		t.Fatalf("bad: %d", c.PullRetries)
+	}
+	if c.PullRetryDelay != 5*time.Second {
+		t.Fatalf("bad: %s", c.PullRetryDelay)
+	}
+
+	// Good retries
+	raw["pull_retries"] = 3
+	raw["pull_retry_delay"] = "1s"
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.PullRetries != 3 {
+		t.Fatalf("bad: %d", c.PullRetries)
+	}
+	if c.PullRetryDelay != time.Second {
+		t.Fatalf("bad: %s", c.PullRetryDelay)
+	}
+
+	// Negative retries
+	raw["pull_retries"] = -1
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_loginPasswordFile(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	if _, err := tf.WriteString("hunter2\n"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+
+	raw := testConfig()
+	raw["login"] = true
+	raw["login_username"] = "foo"
+	raw["login_password_file"] = tf.Name()
+
+	// Password is read from the file
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.LoginPassword != "hunter2" {
+		t.Fatalf("bad: %q", c.LoginPassword)
+	}
+
+	// Conflicts with login_password
+	raw["login_password"] = "bar"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+	delete(raw, "login_password")
+
+	// Missing file
+	raw["login_password_file"] = tf.Name() + ".missing"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_loginEnv(t *testing.T) {
+	defer os.Setenv("PODMAN_LOGIN_USERNAME", os.Getenv("PODMAN_LOGIN_USERNAME"))
+	defer os.Setenv("PODMAN_LOGIN_PASSWORD", os.Getenv("PODMAN_LOGIN_PASSWORD"))
+	os.Setenv("PODMAN_LOGIN_USERNAME", "foo")
+	os.Setenv("PODMAN_LOGIN_PASSWORD", "hunter2")
+
+	raw := testConfig()
+	raw["login"] = true
+
+	// Credentials come from the environment
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.LoginUsername != "foo" || c.LoginPassword != "hunter2" {
+		t.Fatalf("bad: %q %q", c.LoginUsername, c.LoginPassword)
+	}
+
+	// The template wins over the environment
+	raw["login_username"] = "bar"
+	raw["login_password"] = "baz"
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.LoginUsername != "bar" || c.LoginPassword != "baz" {
+		t.Fatalf("bad: %q %q", c.LoginUsername, c.LoginPassword)
+	}
+}
+
+func TestConfig_rootlessWarnings(t *testing.T) {
+	raw := testConfig()
+	raw["privileged"] = true
+	raw["device"] = []string{"/dev/fuse"}
+	raw["volumes"] = map[string]string{"/host": "/guest"}
+
+	// Rootless mode is only known once the build runs, so Prepare doesn't
+	// warn
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// Rootless warns about each option
+	if warns := c.rootlessWarnings(); len(warns) != 3 {
+		t.Fatalf("bad: %#v", warns)
+	}
+
+	// keep-id takes care of the volumes
+	raw["userns"] = "keep-id"
+	c = Config{}
+	c.Prepare(raw)
+	if warns := c.rootlessWarnings(); len(warns) != 2 {
+		t.Fatalf("bad: %#v", warns)
+	}
+
+	// Bind mounts are owned by root too
+	delete(raw, "userns")
+	delete(raw, "volumes")
+	raw["mount"] = []map[string]interface{}{
+		{"type": "bind", "source": "/host", "target": "/guest"},
+	}
+	c = Config{}
+	c.Prepare(raw)
+	if warns := c.rootlessWarnings(); len(warns) != 3 {
+		t.Fatalf("bad: %#v", warns)
+	}
+}
+
+func TestConfigPrepare_registryAuth(t *testing.T) {
+	raw := testConfig()
+	raw["registry_auth"] = []map[string]interface{}{
+		{"server": "quay.io", "username": "foo", "password": "bar"},
+	}
+
+	// Good registry auth
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if len(c.RegistryAuth) != 1 || c.RegistryAuth[0].Server != "quay.io" {
+		t.Fatalf("bad: %#v", c.RegistryAuth)
+	}
+
+	// Conflicts with authfile
+	raw["authfile"] = "auth.json"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+	delete(raw, "authfile")
+
+	// Conflicts with login
+	raw["login"] = true
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+	delete(raw, "login")
+
+	// Missing password
+	raw["registry_auth"] = []map[string]interface{}{
+		{"server": "quay.io", "username": "foo"},
+	}
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_exportFormat(t *testing.T) {
+	raw := testConfig()
+
+	// No format, defaults to tar
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.ExportFormat != "tar" {
+		t.Fatalf("bad: %s", c.ExportFormat)
+	}
+
+	// Good formats
+	for _, format := range []string{"tar", "oci-layout", "squashfs"} {
+		raw["export_format"] = format
+		warns, errs = (&Config{}).Prepare(raw)
+		testConfigOk(t, warns, errs)
+	}
+
+	// Bad format
+	raw["export_format"] = "zip"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_exportCompression(t *testing.T) {
+	raw := testConfig()
+
+	// Good compression, default level
+	raw["export_compression"] = "zstd"
+	warns, errs := (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// Good compression and level
+	raw["export_compression_level"] = 19
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigOk(t, warns, errs)
+
+	// Level out of range for the algorithm
+	raw["export_compression"] = "gzip"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Level without compression
+	delete(raw, "export_compression")
+	raw["export_compression_level"] = 1
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Bad compression
+	raw["export_compression"] = "bzip2"
+	delete(raw, "export_compression_level")
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Level with xz squashfs
+	raw["export_compression"] = "xz"
+	raw["export_compression_level"] = 6
+	raw["export_format"] = "squashfs"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+}
+
+func TestConfigPrepare_stopTimeout(t *testing.T) {
+	raw := testConfig()
+
+	// No timeout, defaults to 10s
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.StopTimeout != 10*time.Second {
+		t.Fatalf("bad: %s", c.StopTimeout)
+	}
+
+	// Timeout set
+	raw["stop_timeout"] = "1m"
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.StopTimeout != time.Minute {
+		t.Fatalf("bad: %s", c.StopTimeout)
+	}
+}
+
+func TestConfigPrepare_commitPause(t *testing.T) {
+	raw := testConfig()
+
+	// No commit_pause set
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if !c.CommitPause {
+		t.Fatal("should pause by default")
+	}
+
+	// Rootless podman can't pause on cgroup v1
+	c.applyPodmanInfo(&PodmanInfo{Rootless: true, CgroupVersion: "v2"})
+	if !c.CommitPause {
+		t.Fatal("should pause with cgroup v2")
+	}
+	c.applyPodmanInfo(&PodmanInfo{Rootless: true, CgroupVersion: "v1"})
+	if c.CommitPause {
+		t.Fatal("should not pause rootless with cgroup v1")
+	}
+
+	// commit_pause set
+	raw["commit_pause"] = false
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.CommitPause {
+		t.Fatal("should not pause")
+	}
+
+	// commit_pause set is kept as is, preflight reports it
+	raw["commit_pause"] = true
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	c.applyPodmanInfo(&PodmanInfo{Rootless: true, CgroupVersion: "v1"})
+	if !c.CommitPause {
+		t.Fatal("should pause when set")
+	}
+}
+
+func TestConfigPrepare_imageDigest(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	raw := testConfig()
+
+	// Digest pins the image
+	raw["image"] = "localhost:5000/foo/bar:1.0"
+	raw["image_digest"] = digest
+	var c Config
+	warns, errs := c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.Image != "localhost:5000/foo/bar@"+digest {
+		t.Fatalf("bad: %s", c.Image)
+	}
+
+	// Digest taken from the image
+	delete(raw, "image_digest")
+	raw["image"] = "bar@" + digest
+	raw["verify_base_digest"] = true
+	c = Config{}
+	warns, errs = c.Prepare(raw)
+	testConfigOk(t, warns, errs)
+	if c.ImageDigest != digest {
+		t.Fatalf("bad: %s", c.ImageDigest)
+	}
+
+	// Conflicting digests
+	raw["image_digest"] = "sha256:" + strings.Repeat("b", 64)
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Invalid digest
+	raw["image"] = "bar"
+	raw["image_digest"] = "latest"
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
+
+	// Verify without digest
+	delete(raw, "image_digest")
+	warns, errs = (&Config{}).Prepare(raw)
+	testConfigErr(t, warns, errs)
 }
@@ -0,0 +1,63 @@
+package podman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// credentialExpiryMargin is how long before their expiry cached credentials
+// are refreshed, so that they don't expire halfway through a pull.
+const credentialExpiryMargin = 30 * time.Second
+
+// execCredential is the output expected from a credential command.
+type execCredential struct {
+	Username  string     `json:"username"`
+	Token     string     `json:"token"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+var credentialCache = struct {
+	sync.Mutex
+	entries map[string]execCredential
+}{entries: make(map[string]execCredential)}
+
+// runCredentialCommand runs command and returns the credentials it prints.
+// The credentials are cached until they expire; credentials without an
+// expiry are cached for the lifetime of the plugin.
+func runCredentialCommand(command []string) (string, string, error) {
+	key := strings.Join(command, "\x00")
+
+	credentialCache.Lock()
+	defer credentialCache.Unlock()
+
+	if cred, ok := credentialCache.entries[key]; ok {
+		if cred.ExpiresAt == nil || time.Now().Add(credentialExpiryMargin).Before(*cred.ExpiresAt) {
+			return cred.Username, cred.Token, nil
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", "", fmt.Errorf("Error running credential command: %s\nStderr: %s",
+			err, stderr.String())
+	}
+
+	var cred execCredential
+	if err := json.Unmarshal(stdout.Bytes(), &cred); err != nil {
+		return "", "", fmt.Errorf("Error parsing credential command output: %s", err)
+	}
+	if cred.Username == "" || cred.Token == "" {
+		return "", "", fmt.Errorf("Credential command must output both a username and a token")
+	}
+
+	credentialCache.entries[key] = cred
+	return cred.Username, cred.Token, nil
+}
@@ -0,0 +1,83 @@
+package podman
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCredentialCommand returns a command printing output, which also counts
+// how many times it ran in the returned file.
+func testCredentialCommand(t *testing.T, output string) ([]string, string) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(td) })
+
+	counter := filepath.Join(td, "counter")
+	script := fmt.Sprintf("echo run >> %s; echo '%s'", counter, output)
+	return []string{"sh", "-c", script}, counter
+}
+
+func testCredentialCommandRuns(t *testing.T, counter string) int {
+	contents, err := ioutil.ReadFile(counter)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return strings.Count(string(contents), "run")
+}
+
+func TestRunCredentialCommand(t *testing.T) {
+	command, counter := testCredentialCommand(t, `{"username": "AWS", "token": "hunter2"}`)
+
+	for i := 0; i < 2; i++ {
+		username, token, err := runCredentialCommand(command)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if username != "AWS" || token != "hunter2" {
+			t.Fatalf("bad: %q %q", username, token)
+		}
+	}
+
+	// verify the credentials were cached
+	if runs := testCredentialCommandRuns(t, counter); runs != 1 {
+		t.Fatalf("bad: %d", runs)
+	}
+}
+
+func TestRunCredentialCommand_expired(t *testing.T) {
+	expiresAt := time.Now().Add(time.Second).Format(time.RFC3339)
+	command, counter := testCredentialCommand(t,
+		fmt.Sprintf(`{"username": "AWS", "token": "hunter2", "expires_at": "%s"}`, expiresAt))
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := runCredentialCommand(command); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// verify the credentials were refreshed since they were about to expire
+	if runs := testCredentialCommandRuns(t, counter); runs != 2 {
+		t.Fatalf("bad: %d", runs)
+	}
+}
+
+func TestRunCredentialCommand_error(t *testing.T) {
+	if _, _, err := runCredentialCommand([]string{"sh", "-c", "exit 1"}); err == nil {
+		t.Fatal("should error")
+	}
+
+	if _, _, err := runCredentialCommand([]string{"echo", "not json"}); err == nil {
+		t.Fatal("should error")
+	}
+
+	if _, _, err := runCredentialCommand([]string{"echo", `{"username": "AWS"}`}); err == nil {
+		t.Fatal("should error")
+	}
+}
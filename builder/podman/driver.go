@@ -1,7 +1,9 @@
 package podman
 
 import (
+	"context"
 	"io"
+	"time"
 
 	"github.com/hashicorp/go-version"
 )
@@ -10,8 +12,9 @@ import (
 // Podman. The Driver interface also allows the steps to be tested since
 // a mock driver can be shimmed in.
 type Driver interface {
-	// Commit the container to a tag
-	Commit(id string, author string, changes []string, message string) (string, error)
+	// Commit the container to a tag, pausing it while committing if pause
+	// is set
+	Commit(id string, author string, changes []string, message string, pause bool) (string, error)
 
 	// Delete an image that is imported into Podman
 	DeleteImage(id string) error
@@ -22,6 +25,23 @@ type Driver interface {
 	// Import imports a container from a tar file
 	Import(path string, changes []string, repo string) (string, error)
 
+	// ImageExists reports whether the image is in local storage.
+	ImageExists(image string) (bool, error)
+
+	// InspectImage returns what podman knows about a local image.
+	InspectImage(image string) (*ImageInspect, error)
+
+	// InheritConfig returns the Dockerfile changes that reproduce the given
+	// instructions, such as ENV or USER, of the configuration of an image.
+	InheritConfig(image string, instructions []string) ([]string, error)
+
+	// InspectContainer returns what podman knows about a container.
+	InspectContainer(id string) (*ContainerInspect, error)
+
+	// RemoteManifest returns the manifest, or manifest list, of the image
+	// in its registry. Only the TLS and auth file options are used.
+	RemoteManifest(image string, options PullOptions) ([]byte, error)
+
 	// IPAddress returns the address of the container that can be used
 	// for external access.
 	IPAddress(id string) (string, error)
@@ -37,32 +57,89 @@ type Driver interface {
 	Logout(repo string) error
 
 	// Pull should pull down the given image.
-	Pull(image string) error
+	Pull(image string, options PullOptions) error
 
-	// Push pushes an image to a Podman index/registry.
-	Push(name string) error
+	// Push pushes an image to a Podman index/registry. If authFile isn't
+	// empty it is used instead of the default auth file.
+	Push(name string, authFile string) error
 
-	// Save an image with the given ID to the given writer.
-	SaveImage(id string, dst io.Writer) error
+	// Save an image with the given ID to the given writer. The format is
+	// passed to `podman save --format`; an empty format uses podman's default.
+	SaveImage(id string, format string, dst io.Writer) error
 
 	// StartContainer starts a container and returns the ID for that container,
 	// along with a potential error.
 	StartContainer(*ContainerConfig) (string, error)
 
+	// CreatePod creates a pod and returns its ID.
+	CreatePod(*PodConfig) (string, error)
+
+	// RemovePod removes a pod along with all of its containers.
+	RemovePod(id string) error
+
+	// StartService starts a service container in the given pod and returns
+	// its ID.
+	StartService(pod string, service *ServiceConfig) (string, error)
+
+	// VolumeExists reports whether a named volume exists.
+	VolumeExists(name string) (bool, error)
+
+	// CreateVolume creates a named volume with the given labels.
+	CreateVolume(name string, labels map[string]string) error
+
+	// ListVolumes returns the named volumes that have all the given labels.
+	ListVolumes(labels map[string]string) ([]Volume, error)
+
+	// RemoveVolume removes a named volume.
+	RemoveVolume(name string) error
+
+	// CreateSecret creates a podman secret holding value and returns its
+	// ID.
+	CreateSecret(name string, value []byte) (string, error)
+
+	// RemoveSecret removes a podman secret.
+	RemoveSecret(id string) error
+
+	// Exec runs a command in a running container and returns its trimmed
+	// output, which is also returned when the command fails.
+	Exec(ctx context.Context, id string, command []string) (string, error)
+
+	// HealthCheck runs the health check of a container, returning an error
+	// if it isn't healthy.
+	HealthCheck(id string) error
+
 	// KillContainer forcibly stops a container.
 	KillContainer(id string) error
 
-	// StopContainer gently stops a container.
-	StopContainer(id string) error
+	// RemoveContainer removes a stopped container along with its anonymous
+	// volumes.
+	RemoveContainer(id string) error
+
+	// StopContainer gently stops a container, killing it if it is still
+	// running once the timeout expires.
+	StopContainer(id string, timeout time.Duration) error
 
 	// TagImage tags the image with the given ID
 	TagImage(id string, repo string, force bool) error
 
+	// UnshareRemoveAll removes a path from within the user namespace of
+	// rootless podman, where the files created by the subordinate UIDs of
+	// the containers can be removed.
+	UnshareRemoveAll(path string) error
+
 	// Verify verifies that the driver can run
 	Verify() error
 
 	// Version reads the Podman version
 	Version() (*version.Version, error)
+
+	// PodmanVersion probes the installed podman once and returns what it
+	// reports about itself.
+	PodmanVersion() (*PodmanVersion, error)
+
+	// PodmanInfo runs podman info once and returns what it reports about
+	// the podman host.
+	PodmanInfo() (*PodmanInfo, error)
 }
 
 // ContainerConfig is the configuration used to start a container.
@@ -76,9 +153,44 @@ type ContainerConfig struct {
 	TmpFs      []string
 	Privileged bool
 	Systemd    string
+	StopSignal string
+	Userns     string
+	Pod        string
+	Mounts     []MountConfig
+	// DisableHTTPProxy keeps podman from setting the proxy variables of the
+	// host in the container.
+	DisableHTTPProxy bool
+	// Secrets maps the podman secrets to mount to their file name in
+	// /run/secrets.
+	Secrets map[string]string
+}
+
+// PodConfig is the configuration of the pod the build container and its
+// services share.
+type PodConfig struct {
+	// Hosts are the names resolving to the pod itself.
+	Hosts  []string
+	Ports  []string
+	Userns string
+}
+
+// PullOptions are the options used to pull an image. Empty values leave the
+// podman defaults in place.
+type PullOptions struct {
+	Platform      string
+	TLSVerify     *bool
+	CertDir       string
+	AuthFile      string
+	DecryptionKey string
 }
 
 // This is the template that is used for the RunCommand in the ContainerConfig.
 type startContainerTemplate struct {
-	Image string
+	Image         string
+	TempDir       string
+	ContainerDir  string
+	BuildName     string
+	ContainerName string
+	Platform      string
+	RunVars       map[string]string
 }
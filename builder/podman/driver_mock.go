@@ -1,7 +1,9 @@
 package podman
 
 import (
+	"context"
 	"io"
+	"time"
 
 	"github.com/hashicorp/go-version"
 )
@@ -10,6 +12,7 @@ import (
 type MockDriver struct {
 	CommitCalled      bool
 	CommitContainerId string
+	CommitPause       bool
 	CommitImageId     string
 	CommitErr         error
 
@@ -23,6 +26,34 @@ type MockDriver struct {
 	ImportId     string
 	ImportErr    error
 
+	ImageExistsCalled bool
+	ImageExistsImage  string
+	ImageExistsResult bool
+	ImageExistsErr    error
+
+	InspectImageCalled bool
+	InspectImageImage  string
+	InspectImageResult *ImageInspect
+	InspectImageErr    error
+
+	InheritConfigCalled       bool
+	InheritConfigImage        string
+	InheritConfigInstructions []string
+	InheritConfigResult       []string
+	InheritConfigErr          error
+
+	InspectContainerCalled bool
+	InspectContainerID     string
+	InspectContainerResult *ContainerInspect
+	InspectContainerErr    error
+
+	RemoteManifestCalled  bool
+	RemoteManifestImage   string
+	RemoteManifestOptions PullOptions
+	RemoteManifestResult  []byte
+	RemoteManifestResults map[string][]byte
+	RemoteManifestErr     error
+
 	IPAddressCalled bool
 	IPAddressID     string
 	IPAddressResult string
@@ -37,6 +68,10 @@ type MockDriver struct {
 	KillID     string
 	KillError  error
 
+	RemoveCalled bool
+	RemoveID     string
+	RemoveError  error
+
 	LoginCalled   bool
 	LoginUsername string
 	LoginPassword string
@@ -47,12 +82,14 @@ type MockDriver struct {
 	LogoutRepo   string
 	LogoutErr    error
 
-	PushCalled bool
-	PushName   string
-	PushErr    error
+	PushCalled   bool
+	PushName     string
+	PushAuthFile string
+	PushErr      error
 
 	SaveImageCalled bool
 	SaveImageId     string
+	SaveImageFormat string
 	SaveImageReader io.Reader
 	SaveImageError  error
 
@@ -62,6 +99,52 @@ type MockDriver struct {
 	TagImageForce   bool
 	TagImageErr     error
 
+	CreatePodCalled bool
+	CreatePodConfig *PodConfig
+	CreatePodID     string
+	CreatePodErr    error
+
+	RemovePodCalled bool
+	RemovePodID     string
+	RemovePodErr    error
+
+	StartServiceCount    int
+	StartServicePod      string
+	StartServiceServices []string
+	StartServiceID       string
+	StartServiceErr      error
+
+	VolumeExistsNames  []string
+	VolumeExistsResult bool
+	VolumeExistsErr    error
+
+	CreateVolumeNames  []string
+	CreateVolumeLabels map[string]string
+	CreateVolumeErr    error
+
+	ListVolumesCalled bool
+	ListVolumesLabels map[string]string
+	ListVolumesResult []Volume
+	ListVolumesErr    error
+
+	RemoveVolumeNames []string
+	RemoveVolumeErr   error
+
+	CreateSecretCount  int
+	CreateSecretNames  []string
+	CreateSecretValues []string
+	CreateSecretErr    error
+
+	RemoveSecretIDs []string
+	RemoveSecretErr error
+
+	ExecCommands [][]string
+	ExecResults  []MockExecResult
+
+	HealthCheckCount int
+	HealthCheckID    string
+	HealthCheckErrs  []error
+
 	ExportReader io.Reader
 	ExportError  error
 	PullError    error
@@ -73,20 +156,35 @@ type MockDriver struct {
 	ExportCalled bool
 	ExportID     string
 	PullCalled   bool
+	PullCount    int
 	PullImage    string
+	PullOptions  PullOptions
 	StartCalled  bool
 	StartConfig  *ContainerConfig
 	StopCalled   bool
 	StopID       string
+	StopTimeout  time.Duration
 	VerifyCalled bool
 
+	UnshareRemoveAllPath string
+	UnshareRemoveAllErr  error
+
 	VersionCalled  bool
 	VersionVersion string
+
+	PodmanVersionCalled bool
+	PodmanVersionResult *PodmanVersion
+	PodmanVersionErr    error
+
+	PodmanInfoCalled bool
+	PodmanInfoResult *PodmanInfo
+	PodmanInfoErr    error
 }
 
-func (d *MockDriver) Commit(id string, author string, changes []string, message string) (string, error) {
+func (d *MockDriver) Commit(id string, author string, changes []string, message string, pause bool) (string, error) {
 	d.CommitCalled = true
 	d.CommitContainerId = id
+	d.CommitPause = pause
 	return d.CommitImageId, d.CommitErr
 }
 
@@ -117,6 +215,34 @@ func (d *MockDriver) Import(path string, changes []string, repo string) (string,
 	return d.ImportId, d.ImportErr
 }
 
+func (d *MockDriver) ImageExists(image string) (bool, error) {
+	d.ImageExistsCalled = true
+	d.ImageExistsImage = image
+	return d.ImageExistsResult, d.ImageExistsErr
+}
+
+func (d *MockDriver) InspectImage(image string) (*ImageInspect, error) {
+	d.InspectImageCalled = true
+	d.InspectImageImage = image
+	if d.InspectImageResult == nil && d.InspectImageErr == nil {
+		return &ImageInspect{}, nil
+	}
+	return d.InspectImageResult, d.InspectImageErr
+}
+
+func (d *MockDriver) InheritConfig(image string, instructions []string) ([]string, error) {
+	d.InheritConfigCalled = true
+	d.InheritConfigImage = image
+	d.InheritConfigInstructions = instructions
+	return d.InheritConfigResult, d.InheritConfigErr
+}
+
+func (d *MockDriver) InspectContainer(id string) (*ContainerInspect, error) {
+	d.InspectContainerCalled = true
+	d.InspectContainerID = id
+	return d.InspectContainerResult, d.InspectContainerErr
+}
+
 func (d *MockDriver) IPAddress(id string) (string, error) {
 	d.IPAddressCalled = true
 	d.IPAddressID = id
@@ -143,21 +269,35 @@ func (d *MockDriver) Logout(r string) error {
 	return d.LogoutErr
 }
 
-func (d *MockDriver) Pull(image string) error {
+func (d *MockDriver) RemoteManifest(image string, options PullOptions) ([]byte, error) {
+	d.RemoteManifestCalled = true
+	d.RemoteManifestImage = image
+	d.RemoteManifestOptions = options
+	if result, ok := d.RemoteManifestResults[image]; ok {
+		return result, d.RemoteManifestErr
+	}
+	return d.RemoteManifestResult, d.RemoteManifestErr
+}
+
+func (d *MockDriver) Pull(image string, options PullOptions) error {
 	d.PullCalled = true
+	d.PullCount += 1
 	d.PullImage = image
+	d.PullOptions = options
 	return d.PullError
 }
 
-func (d *MockDriver) Push(name string) error {
+func (d *MockDriver) Push(name string, authFile string) error {
 	d.PushCalled = true
 	d.PushName = name
+	d.PushAuthFile = authFile
 	return d.PushErr
 }
 
-func (d *MockDriver) SaveImage(id string, dst io.Writer) error {
+func (d *MockDriver) SaveImage(id string, format string, dst io.Writer) error {
 	d.SaveImageCalled = true
 	d.SaveImageId = id
+	d.SaveImageFormat = format
 
 	if d.SaveImageReader != nil {
 		_, err := io.Copy(dst, d.SaveImageReader)
@@ -169,6 +309,94 @@ func (d *MockDriver) SaveImage(id string, dst io.Writer) error {
 	return d.SaveImageError
 }
 
+func (d *MockDriver) CreatePod(config *PodConfig) (string, error) {
+	d.CreatePodCalled = true
+	d.CreatePodConfig = config
+	return d.CreatePodID, d.CreatePodErr
+}
+
+func (d *MockDriver) RemovePod(id string) error {
+	d.RemovePodCalled = true
+	d.RemovePodID = id
+	return d.RemovePodErr
+}
+
+func (d *MockDriver) StartService(pod string, service *ServiceConfig) (string, error) {
+	d.StartServiceCount += 1
+	d.StartServicePod = pod
+	d.StartServiceServices = append(d.StartServiceServices, service.Name)
+	return d.StartServiceID, d.StartServiceErr
+}
+
+func (d *MockDriver) VolumeExists(name string) (bool, error) {
+	d.VolumeExistsNames = append(d.VolumeExistsNames, name)
+	return d.VolumeExistsResult, d.VolumeExistsErr
+}
+
+func (d *MockDriver) CreateVolume(name string, labels map[string]string) error {
+	d.CreateVolumeNames = append(d.CreateVolumeNames, name)
+	d.CreateVolumeLabels = labels
+	return d.CreateVolumeErr
+}
+
+func (d *MockDriver) ListVolumes(labels map[string]string) ([]Volume, error) {
+	d.ListVolumesCalled = true
+	d.ListVolumesLabels = labels
+	return d.ListVolumesResult, d.ListVolumesErr
+}
+
+func (d *MockDriver) RemoveVolume(name string) error {
+	d.RemoveVolumeNames = append(d.RemoveVolumeNames, name)
+	return d.RemoveVolumeErr
+}
+
+// CreateSecret returns the name of the secret as its ID.
+func (d *MockDriver) CreateSecret(name string, value []byte) (string, error) {
+	d.CreateSecretCount += 1
+	d.CreateSecretNames = append(d.CreateSecretNames, name)
+	d.CreateSecretValues = append(d.CreateSecretValues, string(value))
+	if d.CreateSecretErr != nil {
+		return "", d.CreateSecretErr
+	}
+	return name, nil
+}
+
+func (d *MockDriver) RemoveSecret(id string) error {
+	d.RemoveSecretIDs = append(d.RemoveSecretIDs, id)
+	return d.RemoveSecretErr
+}
+
+// MockExecResult is what a command run with MockDriver.Exec returns.
+type MockExecResult struct {
+	Output string
+	Err    error
+}
+
+// Exec returns the results of ExecResults in turn, and succeeds without
+// output once they are exhausted.
+func (d *MockDriver) Exec(ctx context.Context, id string, command []string) (string, error) {
+	d.ExecCommands = append(d.ExecCommands, command)
+	if len(d.ExecResults) == 0 {
+		return "", nil
+	}
+	result := d.ExecResults[0]
+	d.ExecResults = d.ExecResults[1:]
+	return result.Output, result.Err
+}
+
+// HealthCheck returns the errors of HealthCheckErrs in turn, and succeeds
+// once they are exhausted.
+func (d *MockDriver) HealthCheck(id string) error {
+	d.HealthCheckCount += 1
+	d.HealthCheckID = id
+	if len(d.HealthCheckErrs) == 0 {
+		return nil
+	}
+	err := d.HealthCheckErrs[0]
+	d.HealthCheckErrs = d.HealthCheckErrs[1:]
+	return err
+}
+
 func (d *MockDriver) StartContainer(config *ContainerConfig) (string, error) {
 	d.StartCalled = true
 	d.StartConfig = config
@@ -181,9 +409,16 @@ func (d *MockDriver) KillContainer(id string) error {
 	return d.KillError
 }
 
-func (d *MockDriver) StopContainer(id string) error {
+func (d *MockDriver) RemoveContainer(id string) error {
+	d.RemoveCalled = true
+	d.RemoveID = id
+	return d.RemoveError
+}
+
+func (d *MockDriver) StopContainer(id string, timeout time.Duration) error {
 	d.StopCalled = true
 	d.StopID = id
+	d.StopTimeout = timeout
 	return d.StopError
 }
 
@@ -195,6 +430,11 @@ func (d *MockDriver) TagImage(id string, repo string, force bool) error {
 	return d.TagImageErr
 }
 
+func (d *MockDriver) UnshareRemoveAll(path string) error {
+	d.UnshareRemoveAllPath = path
+	return d.UnshareRemoveAllErr
+}
+
 func (d *MockDriver) Verify() error {
 	d.VerifyCalled = true
 	return d.VerifyError
@@ -204,3 +444,13 @@ func (d *MockDriver) Version() (*version.Version, error) {
 	d.VersionCalled = true
 	return version.NewVersion(d.VersionVersion)
 }
+
+func (d *MockDriver) PodmanVersion() (*PodmanVersion, error) {
+	d.PodmanVersionCalled = true
+	return d.PodmanVersionResult, d.PodmanVersionErr
+}
+
+func (d *MockDriver) PodmanInfo() (*PodmanInfo, error) {
+	d.PodmanInfoCalled = true
+	return d.PodmanInfoResult, d.PodmanInfoErr
+}
@@ -2,25 +2,34 @@ package podman
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
 	"os/exec"
-	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
-	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 )
 
 type PodmanDriver struct {
-	Ui  packersdk.Ui
-	Ctx *interpolate.Context
+	Ui packersdk.Ui
 
 	l sync.Mutex
+
+	versionOnce sync.Once
+	version     *PodmanVersion
+	versionErr  error
+
+	infoOnce sync.Once
+	info     *PodmanInfo
+	infoErr  error
 }
 
 func (d *PodmanDriver) DeleteImage(id string) error {
@@ -42,7 +51,7 @@ func (d *PodmanDriver) DeleteImage(id string) error {
 	return nil
 }
 
-func (d *PodmanDriver) Commit(id string, author string, changes []string, message string) (string, error) {
+func (d *PodmanDriver) Commit(id string, author string, changes []string, message string, pause bool) (string, error) {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 
@@ -56,6 +65,7 @@ func (d *PodmanDriver) Commit(id string, author string, changes []string, messag
 	if message != "" {
 		args = append(args, "--message", message)
 	}
+	args = append(args, fmt.Sprintf("--pause=%t", pause))
 	args = append(args, id)
 
 	log.Printf("Committing container with args: %v", args)
@@ -143,130 +153,134 @@ func (d *PodmanDriver) Import(path string, changes []string, repo string) (strin
 	return strings.TrimSpace(stdout.String()), nil
 }
 
-func (d *PodmanDriver) IPAddress(id string) (string, error) {
-	var stderr, stdout bytes.Buffer
-	cmd := exec.Command(
-		"podman",
-		"inspect",
-		"--format",
-		"{{ .NetworkSettings.IPAddress }}",
-		id)
-	cmd.Stdout = &stdout
+func (d *PodmanDriver) ImageExists(image string) (bool, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("podman", "image", "exists", image)
 	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		// podman image exists exits with 1 when the image isn't found
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
 	}
 
-	return strings.TrimSpace(stdout.String()), nil
+	return true, nil
 }
 
-func (d *PodmanDriver) Sha256(id string) (string, error) {
+func (d *PodmanDriver) RemoteManifest(image string, options PullOptions) ([]byte, error) {
+	args := []string{"manifest", "inspect"}
+	if options.TLSVerify != nil {
+		args = append(args, fmt.Sprintf("--tls-verify=%t", *options.TLSVerify))
+	}
+	if options.AuthFile != "" {
+		args = append(args, "--authfile", options.AuthFile)
+	}
+	args = append(args, image)
+
 	var stderr, stdout bytes.Buffer
-	cmd := exec.Command(
-		"podman",
-		"inspect",
-		"--format",
-		"{{ .Id }}",
-		id)
+	cmd := exec.Command("podman", args...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+		return nil, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
 	}
 
-	return strings.TrimSpace(stdout.String()), nil
+	return stdout.Bytes(), nil
 }
 
-func (d *PodmanDriver) Cmd(id string) (string, error) {
-	var stderr, stdout bytes.Buffer
-	cmd := exec.Command(
-		"podman",
-		"inspect",
-		"--format",
-		"{{if .Config.Cmd}} {{json .Config.Cmd}} {{else}} [] {{end}}",
-		id)
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+func (d *PodmanDriver) InspectImage(image string) (*ImageInspect, error) {
+	output, err := d.inspect("image", image)
+	if err != nil {
+		return nil, err
 	}
 
-	return strings.TrimSpace(stdout.String()), nil
+	return parseImageInspect(output)
+}
+
+func (d *PodmanDriver) InheritConfig(image string, instructions []string) ([]string, error) {
+	inspect, err := d.InspectImage(image)
+	if err != nil {
+		return nil, err
+	}
+
+	return imageConfigChanges(inspect, instructions)
+}
+
+func (d *PodmanDriver) InspectContainer(id string) (*ContainerInspect, error) {
+	output, err := d.inspect("container", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseContainerInspect(output)
 }
 
-func (d *PodmanDriver) Entrypoint(id string) (string, error) {
+// inspect runs podman inspect for the given kind of object and returns its
+// JSON output.
+func (d *PodmanDriver) inspect(kind string, id string) ([]byte, error) {
 	var stderr, stdout bytes.Buffer
-	cmd := exec.Command(
-		"podman",
-		"inspect",
-		"--format",
-		"{{if .Config.Entrypoint}} {{json .Config.Entrypoint}} {{else}} [] {{end}}",
-		id)
+	cmd := exec.Command("podman", kind, "inspect", id)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+		return nil, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
 	}
 
-	return strings.TrimSpace(stdout.String()), nil
+	return stdout.Bytes(), nil
 }
 
-func (d *PodmanDriver) Login(repo, user, pass string) error {
-	d.l.Lock()
-
-	version_running, err := d.Version()
+func (d *PodmanDriver) IPAddress(id string) (string, error) {
+	inspect, err := d.InspectContainer(id)
 	if err != nil {
-		d.l.Unlock()
-		return err
+		return "", err
 	}
 
-	// Version 17.07.0 of Podman adds support for the new
-	// `--password-stdin` option which can be used to offer
-	// password via the standard input, rather than passing
-	// the password and/or token using a command line switch.
-	constraint, err := version.NewConstraint(">= 17.07.0")
+	return inspect.NetworkSettings.IPAddress, nil
+}
+
+func (d *PodmanDriver) Sha256(id string) (string, error) {
+	inspect, err := d.InspectImage(id)
 	if err != nil {
+		return "", err
+	}
+
+	return inspect.Id, nil
+}
+
+func (d *PodmanDriver) Login(repo, user, pass string) error {
+	d.l.Lock()
+
+	cmd := loginCommand(repo, user, pass)
+	if err := runAndStream(cmd, d.Ui); err != nil {
 		d.l.Unlock()
 		return err
 	}
 
-	cmd := exec.Command("podman")
-	cmd.Args = append(cmd.Args, "login")
+	return nil
+}
 
+// loginCommand builds the podman login command. The password is always fed
+// through stdin so that it never shows up in the process list or the logs.
+func loginCommand(repo, user, pass string) *exec.Cmd {
+	args := []string{"login"}
 	if user != "" {
-		cmd.Args = append(cmd.Args, "-u", user)
+		args = append(args, "--username", user)
 	}
-
 	if pass != "" {
-		if constraint.Check(version_running) {
-			cmd.Args = append(cmd.Args, "--password-stdin")
-
-			stdin, err := cmd.StdinPipe()
-			if err != nil {
-				d.l.Unlock()
-				return err
-			}
-			_, err = io.WriteString(stdin, pass)
-			if err != nil {
-				return err
-			}
-			stdin.Close()
-		} else {
-			cmd.Args = append(cmd.Args, "-p", pass)
-		}
+		args = append(args, "--password-stdin")
 	}
-
 	if repo != "" {
-		cmd.Args = append(cmd.Args, repo)
+		args = append(args, repo)
 	}
 
-	err = runAndStream(cmd, d.Ui)
-	if err != nil {
-		d.l.Unlock()
-		return err
+	cmd := exec.Command("podman", args...)
+	if pass != "" {
+		cmd.Stdin = strings.NewReader(pass)
 	}
-
-	return nil
+	return cmd
 }
 
 func (d *PodmanDriver) Logout(repo string) error {
@@ -281,19 +295,50 @@ func (d *PodmanDriver) Logout(repo string) error {
 	return err
 }
 
-func (d *PodmanDriver) Pull(image string) error {
-	cmd := exec.Command("podman", "pull", image)
+func (d *PodmanDriver) Pull(image string, options PullOptions) error {
+	args := []string{"pull"}
+	if options.Platform != "" {
+		args = append(args, "--platform", options.Platform)
+	}
+	if options.TLSVerify != nil {
+		args = append(args, fmt.Sprintf("--tls-verify=%t", *options.TLSVerify))
+	}
+	if options.CertDir != "" {
+		args = append(args, "--cert-dir", options.CertDir)
+	}
+	if options.AuthFile != "" {
+		args = append(args, "--authfile", options.AuthFile)
+	}
+	if options.DecryptionKey != "" {
+		args = append(args, "--decryption-key", options.DecryptionKey)
+	}
+	args = append(args, image)
+
+	cmd := exec.Command("podman", args...)
 	return runAndStream(cmd, d.Ui)
 }
 
-func (d *PodmanDriver) Push(name string) error {
-	cmd := exec.Command("podman", "push", name)
+func (d *PodmanDriver) Push(name string, authFile string) error {
+	args := []string{"push"}
+	if authFile != "" {
+		args = append(args, "--authfile", authFile)
+	}
+	args = append(args, name)
+
+	cmd := exec.Command("podman", args...)
 	return runAndStream(cmd, d.Ui)
 }
 
-func (d *PodmanDriver) SaveImage(id string, dst io.Writer) error {
+func (d *PodmanDriver) SaveImage(id string, format string, dst io.Writer) error {
 	var stderr bytes.Buffer
-	cmd := exec.Command("podman", "save", id)
+
+	args := []string{"save"}
+	if format != "" {
+		args = append(args, "--format", format)
+	}
+	args = append(args, id)
+
+	cmd := exec.Command("podman", args...)
 	cmd.Stdout = dst
 	cmd.Stderr = &stderr
 
@@ -312,12 +357,6 @@ func (d *PodmanDriver) SaveImage(id string, dst io.Writer) error {
 }
 
 func (d *PodmanDriver) StartContainer(config *ContainerConfig) (string, error) {
-	// Build up the template data
-	var tplData startContainerTemplate
-	tplData.Image = config.Image
-	ictx := *d.Ctx
-	ictx.Data = &tplData
-
 	// Args that we're going to pass to Podman
 	args := []string{"run"}
 	for _, v := range config.Device {
@@ -333,20 +372,31 @@ func (d *PodmanDriver) StartContainer(config *ContainerConfig) (string, error) {
 		args = append(args, "--privileged")
 	}
 	args = append(args, fmt.Sprintf("--systemd=%s", config.Systemd))
+	if config.StopSignal != "" {
+		args = append(args, "--stop-signal", config.StopSignal)
+	}
+	if config.Pod != "" {
+		// The user namespace is the one of the pod
+		args = append(args, "--pod", config.Pod)
+	} else if config.Userns != "" {
+		args = append(args, fmt.Sprintf("--userns=%s", config.Userns))
+	}
+	if config.DisableHTTPProxy {
+		args = append(args, "--http-proxy=false")
+	}
+	for secret, target := range config.Secrets {
+		args = append(args, "--secret", fmt.Sprintf("%s,target=%s", secret, target))
+	}
 	for _, v := range config.TmpFs {
 		args = append(args, "--tmpfs", v)
 	}
 	for host, guest := range config.Volumes {
 		args = append(args, "-v", fmt.Sprintf("%s:%s", host, guest))
 	}
-	for _, v := range config.RunCommand {
-		v, err := interpolate.Render(v, &ictx)
-		if err != nil {
-			return "", err
-		}
-
-		args = append(args, v)
+	for i := range config.Mounts {
+		args = append(args, config.Mounts[i].args()...)
 	}
+	args = append(args, config.RunCommand...)
 	d.Ui.Message(fmt.Sprintf(
 		"Run command: podman %s", strings.Join(args, " ")))
 
@@ -375,56 +425,263 @@ func (d *PodmanDriver) StartContainer(config *ContainerConfig) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
-func (d *PodmanDriver) StopContainer(id string) error {
-	if err := exec.Command("podman", "stop", id).Run(); err != nil {
-		return err
+// stopSeconds turns a stop timeout into the seconds of podman stop --time,
+// rounding up so that sub-second timeouts don't kill the container right
+// away.
+func stopSeconds(timeout time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(timeout.Seconds())))
+}
+
+func (d *PodmanDriver) StopContainer(id string, timeout time.Duration) error {
+	var stderr bytes.Buffer
+	seconds := stopSeconds(timeout)
+	cmd := exec.Command("podman", "stop", "--time", seconds, id)
+	cmd.Stderr = &stderr
+
+	log.Printf("Stopping container %s with a %ss timeout", id, seconds)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error stopping container: %s\nStderr: %s",
+			err, stderr.String())
 	}
+
 	return nil
 }
 
 func (d *PodmanDriver) KillContainer(id string) error {
-	if err := exec.Command("podman", "kill", id).Run(); err != nil {
-		return err
+	var stderr bytes.Buffer
+	cmd := exec.Command("podman", "kill", id)
+	cmd.Stderr = &stderr
+
+	log.Printf("Killing container: %s", id)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error killing container: %s\nStderr: %s",
+			err, stderr.String())
 	}
 
-	return exec.Command("podman", "rm", id).Run()
+	return nil
 }
 
-func (d *PodmanDriver) TagImage(id string, repo string, force bool) error {
-	args := []string{"tag"}
-
-	// detect running podman version before tagging
-	// flag `force` for podman tagging was removed after Podman 1.12.0
-	// to keep its backward compatibility, we are not going to remove `force`
-	// option, but to ignore it when Podman version >= 1.12.0
-	//
-	// for more detail, please refer to the following links:
-	// - https://docs.podman.com/engine/deprecated/#/f-flag-on-podman-tag
-	// - https://github.com/podman/podman/pull/23090
-	version_running, err := d.Version()
+func (d *PodmanDriver) CreatePod(config *PodConfig) (string, error) {
+	args := []string{"pod", "create"}
+	for _, host := range config.Hosts {
+		args = append(args, "--add-host", host+":127.0.0.1")
+	}
+	for _, port := range config.Ports {
+		args = append(args, "--publish", port)
+	}
+	if config.Userns != "" {
+		args = append(args, fmt.Sprintf("--userns=%s", config.Userns))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("podman", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	log.Printf("Creating pod with args: %v", args)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error creating pod: %s\nStderr: %s",
+			err, stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (d *PodmanDriver) RemovePod(id string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("podman", "pod", "rm", "--force", id)
+	cmd.Stderr = &stderr
+
+	log.Printf("Removing pod: %s", id)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error removing pod: %s\nStderr: %s",
+			err, stderr.String())
+	}
+
+	return nil
+}
+
+func (d *PodmanDriver) StartService(pod string, service *ServiceConfig) (string, error) {
+	args := []string{"run", "-d", "--pod", pod}
+	for key, value := range service.Env {
+		args = append(args, "--env", key+"="+value)
+	}
+	if service.HealthCommand != "" {
+		// The builder runs the health check itself, no need for a timer
+		args = append(args,
+			"--health-cmd", service.HealthCommand,
+			"--health-interval", "disable")
+	}
+	args = append(args, service.Image)
+	args = append(args, service.Command...)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("podman", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	log.Printf("Starting service %s with args: %v", service.Name, args)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error starting service %s: %s\nStderr: %s",
+			service.Name, err, stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (d *PodmanDriver) VolumeExists(name string) (bool, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("podman", "volume", "exists", name)
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		// podman volume exists exits with 1 when the volume isn't found
+		return false, nil
+	}
 	if err != nil {
-		return err
+		return false, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	return true, nil
+}
+
+func (d *PodmanDriver) CreateVolume(name string, labels map[string]string) error {
+	args := []string{"volume", "create"}
+	for k, v := range labels {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, v))
+	}
+	args = append(args, name)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("podman", args...)
+	cmd.Stderr = &stderr
+
+	log.Printf("Creating volume with args: %v", args)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error creating volume: %s\nStderr: %s",
+			err, stderr.String())
 	}
 
-	version_deprecated, err := version.NewVersion("1.12.0")
+	return nil
+}
+
+func (d *PodmanDriver) ListVolumes(labels map[string]string) ([]Volume, error) {
+	args := []string{"volume", "ls", "--format", "json"}
+	for k, v := range labels {
+		args = append(args, "--filter", fmt.Sprintf("label=%s=%s", k, v))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("podman", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Error listing volumes: %s\nStderr: %s",
+			err, stderr.String())
+	}
+
+	return parseVolumes(stdout.Bytes())
+}
+
+func (d *PodmanDriver) RemoveVolume(name string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("podman", "volume", "rm", name)
+	cmd.Stderr = &stderr
+
+	log.Printf("Removing volume: %s", name)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error removing volume: %s\nStderr: %s",
+			err, stderr.String())
+	}
+
+	return nil
+}
+
+func (d *PodmanDriver) CreateSecret(name string, value []byte) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("podman", "secret", "create", name, "-")
+	cmd.Stdin = bytes.NewReader(value)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	log.Printf("Creating secret: %s", name)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error creating secret: %s\nStderr: %s",
+			err, stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (d *PodmanDriver) RemoveSecret(id string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("podman", "secret", "rm", id)
+	cmd.Stderr = &stderr
+
+	log.Printf("Removing secret: %s", id)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error removing secret: %s\nStderr: %s",
+			err, stderr.String())
+	}
+
+	return nil
+}
+
+func (d *PodmanDriver) Exec(ctx context.Context, id string, command []string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "podman", append([]string{"exec", id}, command...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	output := strings.TrimSpace(stdout.String())
 	if err != nil {
-		// should never reach this line
-		return err
+		return output, fmt.Errorf("Error running %s: %s\nStderr: %s",
+			strings.Join(command, " "), err, stderr.String())
+	}
+
+	return output, nil
+}
+
+func (d *PodmanDriver) HealthCheck(id string) error {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("podman", "healthcheck", "run", id)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s", strings.TrimSpace(stdout.String()),
+			strings.TrimSpace(stderr.String()))
 	}
 
+	return nil
+}
+
+func (d *PodmanDriver) RemoveContainer(id string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("podman", "rm", "--volumes", id)
+	cmd.Stderr = &stderr
+
+	log.Printf("Removing container: %s", id)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error removing container: %s\nStderr: %s",
+			err, stderr.String())
+	}
+
+	return nil
+}
+
+func (d *PodmanDriver) TagImage(id string, repo string, force bool) error {
+	// podman tag never had a force flag, moving an existing tag is always
+	// allowed.
 	if force {
-		if version_running.LessThan(version_deprecated) {
-			args = append(args, "-f")
-		} else {
-			// do nothing if Podman version >= 1.12.0
-			log.Printf("[WARN] option: \"force\" will be ignored here")
-			log.Printf("since it was removed after Podman 1.12.0 released")
-		}
+		log.Printf("[WARN] option: \"force\" is ignored, podman always moves existing tags")
 	}
-	args = append(args, id, repo)
 
 	var stderr bytes.Buffer
-	cmd := exec.Command("podman", args...)
+	cmd := exec.Command("podman", "tag", id, repo)
 	cmd.Stderr = &stderr
 
 	if err := cmd.Start(); err != nil {
@@ -440,6 +697,20 @@ func (d *PodmanDriver) TagImage(id string, repo string, force bool) error {
 	return nil
 }
 
+func (d *PodmanDriver) UnshareRemoveAll(path string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("podman", "unshare", "rm", "-rf", "--", path)
+	cmd.Stderr = &stderr
+
+	log.Printf("Removing with podman unshare: %s", path)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error removing %s with podman unshare: %s\nStderr: %s",
+			path, err, stderr.String())
+	}
+
+	return nil
+}
+
 func (d *PodmanDriver) Verify() error {
 	if _, err := exec.LookPath("podman"); err != nil {
 		return err
@@ -449,15 +720,53 @@ func (d *PodmanDriver) Verify() error {
 }
 
 func (d *PodmanDriver) Version() (*version.Version, error) {
-	output, err := exec.Command("podman", "-v").Output()
+	v, err := d.PodmanVersion()
 	if err != nil {
 		return nil, err
 	}
 
-	match := regexp.MustCompile(version.VersionRegexpRaw).FindSubmatch(output)
-	if match == nil {
-		return nil, fmt.Errorf("unknown version: %s", output)
-	}
+	return v.Client, nil
+}
+
+func (d *PodmanDriver) PodmanVersion() (*PodmanVersion, error) {
+	d.versionOnce.Do(func() {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("podman", "version", "--format", "json")
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			d.versionErr = fmt.Errorf("Error probing podman version: %s\nStderr: %s",
+				err, stderr.String())
+			return
+		}
+
+		d.version, d.versionErr = parsePodmanVersion(stdout.Bytes())
+		if d.versionErr == nil {
+			log.Printf("Podman version: %s (service: %v, API: %s)",
+				d.version.Client, d.version.Server, d.version.APIVersion)
+		}
+	})
+
+	return d.version, d.versionErr
+}
+
+func (d *PodmanDriver) PodmanInfo() (*PodmanInfo, error) {
+	d.infoOnce.Do(func() {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("podman", "info", "--format", "json")
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			d.infoErr = fmt.Errorf("Error running podman info: %s\nStderr: %s",
+				err, stderr.String())
+			return
+		}
+
+		d.info, d.infoErr = parsePodmanInfo(stdout.Bytes())
+		if d.infoErr == nil {
+			log.Printf("Podman rootless: %t", d.info.Rootless)
+		}
+	})
 
-	return version.NewVersion(string(match[0]))
+	return d.info, d.infoErr
 }
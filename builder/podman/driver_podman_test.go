@@ -0,0 +1,124 @@
+package podman
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestLoginCommand(t *testing.T) {
+	cmd := loginCommand("quay.io", "foo", "hunter2")
+
+	for _, arg := range cmd.Args {
+		if strings.Contains(arg, "hunter2") {
+			t.Fatalf("password in argv: %#v", cmd.Args)
+		}
+	}
+
+	expected := []string{"podman", "login", "--username", "foo", "--password-stdin", "quay.io"}
+	if strings.Join(cmd.Args, " ") != strings.Join(expected, " ") {
+		t.Fatalf("bad: %#v", cmd.Args)
+	}
+
+	stdin, err := ioutil.ReadAll(cmd.Stdin)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(stdin) != "hunter2" {
+		t.Fatalf("bad: %q", stdin)
+	}
+}
+
+func TestLoginCommand_noPassword(t *testing.T) {
+	cmd := loginCommand("quay.io", "", "")
+
+	expected := []string{"podman", "login", "quay.io"}
+	if strings.Join(cmd.Args, " ") != strings.Join(expected, " ") {
+		t.Fatalf("bad: %#v", cmd.Args)
+	}
+	if cmd.Stdin != nil {
+		t.Fatal("stdin should not be set")
+	}
+}
+
+func TestStopSeconds(t *testing.T) {
+	cases := map[time.Duration]string{
+		0:                       "0",
+		500 * time.Millisecond:  "1",
+		10 * time.Second:        "10",
+		1500 * time.Millisecond: "2",
+	}
+	for timeout, expected := range cases {
+		if seconds := stopSeconds(timeout); seconds != expected {
+			t.Fatalf("%s: expected %s, got %s", timeout, expected, seconds)
+		}
+	}
+}
+
+func TestPodmanDriver_Login(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	// A fake podman that echoes its arguments, like a verbose podman would,
+	// and only succeeds if the password came through stdin.
+	script := "#!/bin/sh\necho \"$@\"\nread password\n[ \"$password\" = hunter2 ]\n"
+	if err := ioutil.WriteFile(filepath.Join(td, "podman"), []byte(script), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", td+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	out := new(bytes.Buffer)
+	driver := &PodmanDriver{Ui: &packersdk.BasicUi{
+		Reader:      new(bytes.Buffer),
+		Writer:      out,
+		ErrorWriter: out,
+	}}
+
+	if err := driver.Login("quay.io", "foo", "hunter2"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer driver.l.Unlock()
+
+	if !strings.Contains(out.String(), "--password-stdin") {
+		t.Fatalf("bad: %s", out.String())
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Fatalf("password in ui: %s", out.String())
+	}
+}
+
+func TestPodmanDriver_LoginError(t *testing.T) {
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", "")
+
+	driver := &PodmanDriver{Ui: &packersdk.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}}
+
+	if err := driver.Login("quay.io", "foo", "hunter2"); err == nil {
+		t.Fatal("should error")
+	}
+
+	// A failed login must not keep the driver locked
+	unlocked := make(chan struct{})
+	go func() {
+		driver.l.Lock()
+		close(unlocked)
+	}()
+	select {
+	case <-unlocked:
+	case <-time.After(time.Second):
+		t.Fatal("driver is still locked")
+	}
+}
@@ -12,15 +12,14 @@ func runAndStream(cmd *exec.Cmd, ui packersdk.Ui) error {
 	args := make([]string, len(cmd.Args)-1)
 	copy(args, cmd.Args[1:])
 
-	// Scrub password from the log output.
-	capturedPassword := ""
+	// Scrub passwords and keys from the log output.
+	var sensitive []string
 	for i, v := range args {
-		if v == "-p" || v == "--password" {
-			capturedPassword = args[i+1]
-			break
+		if i+1 < len(args) && (v == "-p" || v == "--password" || v == "--decryption-key") {
+			sensitive = append(sensitive, args[i+1])
 		}
 	}
 
 	// run local command and stream output to UI.
-	return localexec.RunAndStream(cmd, ui, []string{capturedPassword})
+	return localexec.RunAndStream(cmd, ui, sensitive)
 }
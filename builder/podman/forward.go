@@ -0,0 +1,43 @@
+package podman
+
+import (
+	"fmt"
+	"os"
+	"path"
+)
+
+// proxyEnvVars are the proxy variables forwarded with forward_proxy_env, in
+// both of the cases tools look for.
+var proxyEnvVars = []string{
+	"HTTP_PROXY", "http_proxy",
+	"HTTPS_PROXY", "https_proxy",
+	"NO_PROXY", "no_proxy",
+}
+
+// sshAgentSocket returns where the SSH agent socket of the host is mounted
+// in the container. It lives in the mounted temporary directory, so that the
+// mount point isn't created in the container filesystem.
+func sshAgentSocket(config *Config) string {
+	return path.Join(config.ContainerDir, "ssh-agent.sock")
+}
+
+// execEnv returns the environment variables set for the exec sessions of the
+// provisioners, in the `podman exec --env` format. They are only known to
+// the exec sessions, so they never end up in the committed configuration.
+func execEnv(config *Config) []string {
+	var env []string
+	if config.ForwardSSHAgent {
+		env = append(env, fmt.Sprintf("SSH_AUTH_SOCK=%s", sshAgentSocket(config)))
+	}
+	if config.ForwardProxyEnv {
+		for _, name := range proxyEnvVars {
+			// Only the name is given, podman reads the value from its own
+			// environment so that proxy credentials stay out of the
+			// command line.
+			if _, ok := os.LookupEnv(name); ok {
+				env = append(env, name)
+			}
+		}
+	}
+	return env
+}
@@ -0,0 +1,152 @@
+package podman
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// inheritableInstructions are the instructions inherit_config accepts. The
+// ones that can only appear once are replaced by the user's changes rather
+// than being inherited alongside them.
+var inheritableInstructions = map[string]bool{
+	"CMD":         true,
+	"ENTRYPOINT":  true,
+	"ENV":         false,
+	"EXPOSE":      false,
+	"HEALTHCHECK": true,
+	"LABEL":       false,
+	"STOPSIGNAL":  true,
+	"USER":        true,
+	"WORKDIR":     true,
+}
+
+// changeInstruction returns the instruction of a Dockerfile change, in upper
+// case, for example ENV for "env FOO bar".
+func changeInstruction(change string) string {
+	fields := strings.Fields(change)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// imageConfigChanges returns the Dockerfile changes that reproduce the given
+// instructions of the image configuration.
+func imageConfigChanges(image *ImageInspect, instructions []string) ([]string, error) {
+	var changes []string
+	for _, instruction := range instructions {
+		c, err := imageConfigChange(image, strings.ToUpper(instruction))
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+	return changes, nil
+}
+
+func imageConfigChange(image *ImageInspect, instruction string) ([]string, error) {
+	config := image.Config
+	switch instruction {
+	case "CMD", "ENTRYPOINT":
+		args := config.Cmd
+		if instruction == "ENTRYPOINT" {
+			args = config.Entrypoint
+		}
+		if len(args) == 0 {
+			return nil, nil
+		}
+		value, err := jsonArray(args)
+		if err != nil {
+			return nil, err
+		}
+		return []string{instruction + " " + value}, nil
+	case "ENV":
+		var changes []string
+		for _, env := range config.Env {
+			// Podman writes KEY=VALUE, the change uses the KEY VALUE form so
+			// that values may contain spaces
+			kv := strings.SplitN(env, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("Malformed environment variable in base image: %q", env)
+			}
+			changes = append(changes, fmt.Sprintf("ENV %s %s", kv[0], kv[1]))
+		}
+		return changes, nil
+	case "EXPOSE":
+		if len(config.ExposedPorts) == 0 {
+			return nil, nil
+		}
+		ports := make([]string, 0, len(config.ExposedPorts))
+		for port := range config.ExposedPorts {
+			ports = append(ports, port)
+		}
+		sort.Strings(ports)
+		return []string{"EXPOSE " + strings.Join(ports, " ")}, nil
+	case "HEALTHCHECK":
+		if image.HealthCheck == nil || len(image.HealthCheck.Test) == 0 {
+			return nil, nil
+		}
+		return []string{healthCheckChange(image.HealthCheck)}, nil
+	case "LABEL":
+		keys := make([]string, 0, len(config.Labels))
+		for key := range config.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		changes := make([]string, 0, len(keys))
+		for _, key := range keys {
+			changes = append(changes, fmt.Sprintf("LABEL %q=%q", key, config.Labels[key]))
+		}
+		return changes, nil
+	case "STOPSIGNAL", "USER", "WORKDIR":
+		value := map[string]string{
+			"STOPSIGNAL": config.StopSignal,
+			"USER":       config.User,
+			"WORKDIR":    config.WorkingDir,
+		}[instruction]
+		if value == "" {
+			return nil, nil
+		}
+		return []string{instruction + " " + value}, nil
+	default:
+		return nil, fmt.Errorf("Instruction %s can't be inherited from the base image", instruction)
+	}
+}
+
+// healthCheckChange formats a health check as a HEALTHCHECK instruction.
+func healthCheckChange(hc *ImageHealthCheck) string {
+	if hc.Test[0] == "NONE" {
+		return "HEALTHCHECK NONE"
+	}
+
+	var options []string
+	for _, option := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"interval", hc.Interval},
+		{"timeout", hc.Timeout},
+		{"start-period", hc.StartPeriod},
+	} {
+		if option.value != 0 {
+			options = append(options, fmt.Sprintf("--%s=%s", option.name, option.value))
+		}
+	}
+	if hc.Retries != 0 {
+		options = append(options, fmt.Sprintf("--retries=%d", hc.Retries))
+	}
+
+	// The test is either ["CMD-SHELL", "command"] or ["CMD", args...]
+	command := ""
+	if hc.Test[0] == "CMD-SHELL" && len(hc.Test) == 2 {
+		command = "CMD " + hc.Test[1]
+	} else {
+		args, _ := jsonArray(hc.Test[1:])
+		command = "CMD " + args
+	}
+
+	return strings.TrimSpace("HEALTHCHECK " + strings.Join(options, " ") + " " + command)
+}
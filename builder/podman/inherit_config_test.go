@@ -0,0 +1,95 @@
+package podman
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangeInstruction(t *testing.T) {
+	cases := map[string]string{
+		"CMD [\"nginx\"]":  "CMD",
+		"  env FOO bar":    "ENV",
+		"Healthcheck NONE": "HEALTHCHECK",
+		"":                 "",
+	}
+
+	for change, expected := range cases {
+		if instruction := changeInstruction(change); instruction != expected {
+			t.Fatalf("bad instruction for %q: %s", change, instruction)
+		}
+	}
+}
+
+func TestImageConfigChanges(t *testing.T) {
+	image := &ImageInspect{
+		Config: ImageInspectConfig{
+			User:         "nobody",
+			Env:          []string{"PATH=/usr/bin", "GREETING=hello world"},
+			Cmd:          []string{"nginx", "-g", "daemon off;"},
+			WorkingDir:   "/srv",
+			Labels:       map[string]string{"version": "1.0", "maintainer": "Jane Doe"},
+			ExposedPorts: map[string]struct{}{"443/tcp": {}, "80/tcp": {}},
+			StopSignal:   "SIGQUIT",
+		},
+		HealthCheck: &ImageHealthCheck{
+			Test:     []string{"CMD-SHELL", "curl -f http://localhost/"},
+			Interval: 30 * time.Second,
+			Retries:  3,
+		},
+	}
+
+	changes, err := imageConfigChanges(image, []string{
+		"cmd", "ENTRYPOINT", "ENV", "EXPOSE", "HEALTHCHECK", "LABEL", "STOPSIGNAL", "USER", "WORKDIR",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		`CMD ["nginx","-g","daemon off;"]`,
+		`ENV PATH /usr/bin`,
+		`ENV GREETING hello world`,
+		`EXPOSE 443/tcp 80/tcp`,
+		`HEALTHCHECK --interval=30s --retries=3 CMD curl -f http://localhost/`,
+		`LABEL "maintainer"="Jane Doe"`,
+		`LABEL "version"="1.0"`,
+		`STOPSIGNAL SIGQUIT`,
+		`USER nobody`,
+		`WORKDIR /srv`,
+	}
+	if strings.Join(changes, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("bad: %#v", changes)
+	}
+}
+
+func TestImageConfigChanges_error(t *testing.T) {
+	image := &ImageInspect{}
+	if _, err := imageConfigChanges(image, []string{"RUN"}); err == nil {
+		t.Fatal("should error")
+	}
+
+	image.Config.Env = []string{"BROKEN"}
+	if _, err := imageConfigChanges(image, []string{"ENV"}); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestHealthCheckChange(t *testing.T) {
+	cases := []struct {
+		hc       ImageHealthCheck
+		expected string
+	}{
+		{ImageHealthCheck{Test: []string{"NONE"}}, "HEALTHCHECK NONE"},
+		{
+			ImageHealthCheck{Test: []string{"CMD", "/healthz", "-q"}, Timeout: 5 * time.Second},
+			`HEALTHCHECK --timeout=5s CMD ["/healthz","-q"]`,
+		},
+	}
+
+	for _, c := range cases {
+		if change := healthCheckChange(&c.hc); change != c.expected {
+			t.Fatalf("bad: %s", change)
+		}
+	}
+}
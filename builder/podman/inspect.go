@@ -0,0 +1,107 @@
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ImageInspect is what `podman image inspect` reports about an image.
+type ImageInspect struct {
+	Id          string
+	Digest      string
+	RepoTags    []string
+	RepoDigests []string
+	Size        int64
+	Config      ImageInspectConfig
+	RootFS      struct {
+		Layers []string
+	}
+	HealthCheck *ImageHealthCheck
+}
+
+// ImageHealthCheck is the health check of an image. Durations are in
+// nanoseconds, which is also how podman reports them.
+type ImageHealthCheck struct {
+	Test        []string
+	Interval    time.Duration
+	Timeout     time.Duration
+	StartPeriod time.Duration
+	Retries     int
+}
+
+// ImageInspectConfig is the configuration containers created from the image
+// start with.
+type ImageInspectConfig struct {
+	User         string
+	Env          []string
+	Cmd          []string
+	Entrypoint   []string
+	WorkingDir   string
+	Labels       map[string]string
+	ExposedPorts map[string]struct{}
+	Volumes      map[string]struct{}
+	StopSignal   string
+}
+
+// ContainerInspect is what `podman container inspect` reports about a
+// container.
+type ContainerInspect struct {
+	Id     string
+	Image  string
+	Config struct {
+		User       string
+		Env        []string
+		WorkingDir string
+		Labels     map[string]string
+	}
+	State struct {
+		Status  string
+		Running bool
+	}
+	NetworkSettings struct {
+		IPAddress string
+	}
+}
+
+// parseImageInspect parses the output of `podman image inspect` for a single
+// image.
+func parseImageInspect(output []byte) (*ImageInspect, error) {
+	var images []ImageInspect
+	if err := json.Unmarshal(output, &images); err != nil {
+		return nil, fmt.Errorf("Error parsing image inspect: %s", err)
+	}
+	if len(images) != 1 {
+		return nil, fmt.Errorf("Expected to inspect one image, got %d", len(images))
+	}
+
+	return &images[0], nil
+}
+
+// parseContainerInspect parses the output of `podman container inspect` for
+// a single container.
+func parseContainerInspect(output []byte) (*ContainerInspect, error) {
+	var containers []ContainerInspect
+	if err := json.Unmarshal(output, &containers); err != nil {
+		return nil, fmt.Errorf("Error parsing container inspect: %s", err)
+	}
+	if len(containers) != 1 {
+		return nil, fmt.Errorf("Expected to inspect one container, got %d", len(containers))
+	}
+
+	return &containers[0], nil
+}
+
+// jsonArray formats args as a JSON array, the exec form of Dockerfile
+// instructions such as CMD and ENTRYPOINT.
+func jsonArray(args []string) (string, error) {
+	if args == nil {
+		args = []string{}
+	}
+
+	out, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,103 @@
+package podman
+
+import (
+	"testing"
+)
+
+func TestParseImageInspect(t *testing.T) {
+	output := `[
+  {
+    "Id": "5f5f8c9b7a6d",
+    "Digest": "sha256:aaaa",
+    "RepoTags": ["docker.io/library/ubuntu:latest"],
+    "RepoDigests": ["docker.io/library/ubuntu@sha256:aaaa"],
+    "Size": 80000000,
+    "Config": {
+      "User": "nobody",
+      "Env": ["PATH=/usr/bin"],
+      "Cmd": ["/bin/bash"],
+      "WorkingDir": "/srv",
+      "Labels": {"version": "1.0"},
+      "ExposedPorts": {"80/tcp": {}},
+      "StopSignal": "SIGTERM"
+    },
+    "RootFS": {
+      "Type": "layers",
+      "Layers": ["sha256:bbbb"]
+    }
+  }
+]`
+
+	image, err := parseImageInspect([]byte(output))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if image.Id != "5f5f8c9b7a6d" || image.Digest != "sha256:aaaa" || image.Size != 80000000 {
+		t.Fatalf("bad: %#v", image)
+	}
+	if len(image.RepoDigests) != 1 || len(image.RootFS.Layers) != 1 {
+		t.Fatalf("bad: %#v", image)
+	}
+	if image.Config.User != "nobody" || image.Config.WorkingDir != "/srv" {
+		t.Fatalf("bad: %#v", image.Config)
+	}
+	if len(image.Config.Cmd) != 1 || image.Config.Entrypoint != nil {
+		t.Fatalf("bad: %#v", image.Config)
+	}
+	if _, ok := image.Config.ExposedPorts["80/tcp"]; !ok {
+		t.Fatalf("bad: %#v", image.Config.ExposedPorts)
+	}
+	if image.Config.Labels["version"] != "1.0" {
+		t.Fatalf("bad: %#v", image.Config.Labels)
+	}
+}
+
+func TestParseImageInspect_error(t *testing.T) {
+	for _, output := range []string{"", "{}", "[]", "[{}, {}]"} {
+		if _, err := parseImageInspect([]byte(output)); err == nil {
+			t.Fatalf("should error: %q", output)
+		}
+	}
+}
+
+func TestParseContainerInspect(t *testing.T) {
+	output := `[
+  {
+    "Id": "abcdef",
+    "Image": "5f5f8c9b7a6d",
+    "State": {"Status": "running", "Running": true},
+    "Config": {
+      "User": "1000:1000",
+      "Entrypoint": "/bin/sh",
+      "Labels": {"version": "1.0"}
+    },
+    "NetworkSettings": {"IPAddress": "10.88.0.2"}
+  }
+]`
+
+	container, err := parseContainerInspect([]byte(output))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if container.Id != "abcdef" || container.Image != "5f5f8c9b7a6d" {
+		t.Fatalf("bad: %#v", container)
+	}
+	if !container.State.Running {
+		t.Fatalf("bad: %#v", container.State)
+	}
+	if container.Config.User != "1000:1000" {
+		t.Fatalf("bad: %#v", container.Config)
+	}
+	if container.NetworkSettings.IPAddress != "10.88.0.2" {
+		t.Fatalf("bad: %#v", container.NetworkSettings)
+	}
+}
+
+func TestJsonArray(t *testing.T) {
+	if s, _ := jsonArray(nil); s != "[]" {
+		t.Fatalf("bad: %s", s)
+	}
+	if s, _ := jsonArray([]string{"nginx", "-g", "daemon off;"}); s != `["nginx","-g","daemon off;"]` {
+		t.Fatalf("bad: %s", s)
+	}
+}
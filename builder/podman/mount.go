@@ -0,0 +1,85 @@
+//go:generate packer-sdc struct-markdown
+
+package podman
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MountConfig describes a mount of the build container, rendered to `podman
+// run --mount`. Unlike `volumes`, mounts take options, and the same source
+// can be mounted more than once.
+type MountConfig struct {
+	// The type of the mount: `bind`, `volume`, `tmpfs`, `image` or
+	// `overlay`.
+	Type string `mapstructure:"type" required:"true"`
+	// What to mount: a host path for `bind` and `overlay` mounts, a volume
+	// name for `volume` mounts, and an image for `image` mounts. `tmpfs`
+	// mounts have no source.
+	Source string `mapstructure:"source" required:"false"`
+	// The absolute path the mount is mounted at in the container.
+	Target string `mapstructure:"target" required:"true"`
+	// Options of the mount, in the `podman run --mount` format, for example
+	// `ro`, `bind-propagation=rslave`, `U` or `tmpfs-size=64m`. `z` and `Z`
+	// are shorthands for `relabel=shared` and `relabel=private`.
+	Options []string `mapstructure:"options" required:"false"`
+}
+
+func (m *MountConfig) Prepare() []error {
+	var errs []error
+	if !strings.HasPrefix(m.Target, "/") {
+		errs = append(errs, fmt.Errorf("mount %q: target must be an absolute path", m.Target))
+	}
+
+	switch m.Type {
+	case "bind", "overlay":
+		if !strings.HasPrefix(m.Source, "/") {
+			errs = append(errs, fmt.Errorf("mount %q: the source of %s mounts must be an absolute path", m.Target, m.Type))
+		}
+	case "volume", "image":
+		if m.Source == "" {
+			errs = append(errs, fmt.Errorf("mount %q: source must be specified for %s mounts", m.Target, m.Type))
+		}
+	case "tmpfs":
+		if m.Source != "" {
+			errs = append(errs, fmt.Errorf("mount %q: tmpfs mounts have no source", m.Target))
+		}
+	default:
+		errs = append(errs, fmt.Errorf(
+			"mount %q: type must be one of bind, volume, tmpfs, image or overlay, got %q", m.Target, m.Type))
+	}
+
+	for _, option := range m.Options {
+		if option == "" || strings.Contains(option, ",") {
+			errs = append(errs, fmt.Errorf("mount %q: invalid option %q", m.Target, option))
+		}
+	}
+
+	return errs
+}
+
+// args returns the podman run arguments of the mount. podman doesn't take
+// overlay mounts with --mount, they are rendered to --volume instead.
+func (m *MountConfig) args() []string {
+	if m.Type == "overlay" {
+		spec := fmt.Sprintf("%s:%s:%s", m.Source, m.Target, strings.Join(append([]string{"O"}, m.Options...), ","))
+		return []string{"--volume", spec}
+	}
+
+	fields := []string{"type=" + m.Type}
+	if m.Source != "" {
+		fields = append(fields, "source="+m.Source)
+	}
+	fields = append(fields, "target="+m.Target)
+	for _, option := range m.Options {
+		switch option {
+		case "z":
+			option = "relabel=shared"
+		case "Z":
+			option = "relabel=private"
+		}
+		fields = append(fields, option)
+	}
+	return []string{"--mount", strings.Join(fields, ",")}
+}
@@ -0,0 +1,40 @@
+package podman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMountConfigArgs(t *testing.T) {
+	cases := []struct {
+		mount    MountConfig
+		expected []string
+	}{
+		{
+			MountConfig{Type: "bind", Source: "/src", Target: "/src", Options: []string{"ro", "Z", "bind-propagation=rslave"}},
+			[]string{"--mount", "type=bind,source=/src,target=/src,ro,relabel=private,bind-propagation=rslave"},
+		},
+		{
+			MountConfig{Type: "volume", Source: "data", Target: "/data", Options: []string{"U"}},
+			[]string{"--mount", "type=volume,source=data,target=/data,U"},
+		},
+		{
+			MountConfig{Type: "tmpfs", Target: "/scratch"},
+			[]string{"--mount", "type=tmpfs,target=/scratch"},
+		},
+		{
+			MountConfig{Type: "image", Source: "fedora", Target: "/fedora", Options: []string{"rw=false"}},
+			[]string{"--mount", "type=image,source=fedora,target=/fedora,rw=false"},
+		},
+		{
+			MountConfig{Type: "overlay", Source: "/src", Target: "/src", Options: []string{"z"}},
+			[]string{"--volume", "/src:/src:O,z"},
+		},
+	}
+
+	for _, tc := range cases {
+		if args := tc.mount.args(); !reflect.DeepEqual(args, tc.expected) {
+			t.Errorf("bad: %#v, expected %#v", args, tc.expected)
+		}
+	}
+}
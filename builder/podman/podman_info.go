@@ -0,0 +1,70 @@
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PodmanInfo is what `podman info` reports about the podman host.
+type PodmanInfo struct {
+	// Rootless is true if podman runs containers as an unprivileged user.
+	Rootless bool
+	// CgroupVersion is either v1 or v2.
+	CgroupVersion string
+	// OS and Arch are the platform of the podman host, for example linux
+	// and amd64.
+	OS   string
+	Arch string
+	// GraphDriverName is the storage driver, for example overlay or vfs.
+	GraphDriverName string
+	// GraphRoot is where images and containers are stored.
+	GraphRoot string
+	// GraphRootAllocated and GraphRootUsed are the size of the filesystem
+	// holding GraphRoot and how much of it is used, in bytes. They are zero
+	// with podman before 4.0.
+	GraphRootAllocated uint64
+	GraphRootUsed      uint64
+}
+
+// GraphRootFree returns how many bytes are left in the graph root, or zero if
+// podman doesn't tell.
+func (i *PodmanInfo) GraphRootFree() uint64 {
+	if i.GraphRootAllocated < i.GraphRootUsed {
+		return 0
+	}
+	return i.GraphRootAllocated - i.GraphRootUsed
+}
+
+// parsePodmanInfo parses the output of `podman info --format json`.
+func parsePodmanInfo(output []byte) (*PodmanInfo, error) {
+	var raw struct {
+		Host struct {
+			CgroupVersion string `json:"cgroupVersion"`
+			OS            string `json:"os"`
+			Arch          string `json:"arch"`
+			Security      struct {
+				Rootless bool `json:"rootless"`
+			} `json:"security"`
+		} `json:"host"`
+		Store struct {
+			GraphDriverName    string `json:"graphDriverName"`
+			GraphRoot          string `json:"graphRoot"`
+			GraphRootAllocated uint64 `json:"graphRootAllocated"`
+			GraphRootUsed      uint64 `json:"graphRootUsed"`
+		} `json:"store"`
+	}
+	if err := json.Unmarshal(output, &raw); err != nil {
+		return nil, fmt.Errorf("Error parsing podman info: %s", err)
+	}
+
+	return &PodmanInfo{
+		Rootless:           raw.Host.Security.Rootless,
+		CgroupVersion:      raw.Host.CgroupVersion,
+		OS:                 raw.Host.OS,
+		Arch:               raw.Host.Arch,
+		GraphDriverName:    raw.Store.GraphDriverName,
+		GraphRoot:          raw.Store.GraphRoot,
+		GraphRootAllocated: raw.Store.GraphRootAllocated,
+		GraphRootUsed:      raw.Store.GraphRootUsed,
+	}, nil
+}
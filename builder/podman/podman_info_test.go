@@ -0,0 +1,54 @@
+package podman
+
+import (
+	"testing"
+)
+
+func TestParsePodmanInfo(t *testing.T) {
+	output := `{
+  "host": {
+    "arch": "amd64",
+    "cgroupVersion": "v2",
+    "os": "linux",
+    "security": {
+      "rootless": true,
+      "seccompEnabled": true
+    }
+  },
+  "store": {
+    "graphDriverName": "overlay",
+    "graphRoot": "/home/packer/.local/share/containers/storage",
+    "graphRootAllocated": 1000,
+    "graphRootUsed": 400
+  }
+}`
+
+	info, err := parsePodmanInfo([]byte(output))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !info.Rootless {
+		t.Fatal("should be rootless")
+	}
+	if info.CgroupVersion != "v2" {
+		t.Fatalf("bad: %s", info.CgroupVersion)
+	}
+	if info.OS != "linux" || info.Arch != "amd64" {
+		t.Fatalf("bad: %s/%s", info.OS, info.Arch)
+	}
+	if info.GraphDriverName != "overlay" {
+		t.Fatalf("bad: %s", info.GraphDriverName)
+	}
+	if info.GraphRoot != "/home/packer/.local/share/containers/storage" {
+		t.Fatalf("bad: %s", info.GraphRoot)
+	}
+	if info.GraphRootFree() != 600 {
+		t.Fatalf("bad: %d", info.GraphRootFree())
+	}
+}
+
+func TestParsePodmanInfo_error(t *testing.T) {
+	if _, err := parsePodmanInfo([]byte("host: foo")); err == nil {
+		t.Fatal("should error")
+	}
+}
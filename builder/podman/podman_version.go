@@ -0,0 +1,122 @@
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// podmanFeatures maps the podman features this builder may rely on to the
+// podman version that introduced them.
+var podmanFeatures = map[string]string{
+	"--systemd=always": "2.0.0",
+	"--secret":         "3.2.0",
+}
+
+// PodmanVersion is what `podman version` reports about the installed podman.
+type PodmanVersion struct {
+	// Client is the version of the podman binary.
+	Client *version.Version
+	// Server is the version of the podman service, only set when talking
+	// to a remote podman.
+	Server *version.Version
+	// APIVersion is the version of the API the client speaks.
+	APIVersion string
+}
+
+type podmanVersionComponent struct {
+	Version    string
+	APIVersion json.RawMessage
+}
+
+// parsePodmanVersion parses the output of `podman version --format json`.
+// Podman before 2.0 printed a single flat object instead of a client and
+// server pair, so both layouts are accepted.
+func parsePodmanVersion(output []byte) (*PodmanVersion, error) {
+	var raw struct {
+		Client *podmanVersionComponent
+		Server *podmanVersionComponent
+		podmanVersionComponent
+	}
+	if err := json.Unmarshal(output, &raw); err != nil {
+		return nil, fmt.Errorf("Error parsing podman version: %s", err)
+	}
+
+	client := &raw.podmanVersionComponent
+	if raw.Client != nil {
+		client = raw.Client
+	}
+
+	v := &PodmanVersion{
+		APIVersion: strings.Trim(string(client.APIVersion), `"`),
+	}
+
+	var err error
+	if v.Client, err = version.NewVersion(client.Version); err != nil {
+		return nil, fmt.Errorf("Error parsing podman version %q: %s", client.Version, err)
+	}
+	if raw.Server != nil && raw.Server.Version != "" {
+		if v.Server, err = version.NewVersion(raw.Server.Version); err != nil {
+			return nil, fmt.Errorf("Error parsing podman service version %q: %s", raw.Server.Version, err)
+		}
+	}
+
+	return v, nil
+}
+
+// Require returns an error if the installed podman is too old for feature.
+// When talking to a remote podman, the service is the one that matters.
+func (v *PodmanVersion) Require(feature string) error {
+	minimum, ok := podmanFeatures[feature]
+	if !ok {
+		return fmt.Errorf("unknown podman feature: %s", feature)
+	}
+
+	running := v.Client
+	if v.Server != nil {
+		running = v.Server
+	}
+
+	if running.LessThan(version.Must(version.NewVersion(minimum))) {
+		return fmt.Errorf("%s requires podman %s or newer, but podman %s is installed",
+			feature, minimum, running)
+	}
+
+	return nil
+}
+
+// requiredPodmanFeatures returns the podman features needed to honor config.
+func requiredPodmanFeatures(config *Config) []string {
+	var features []string
+	if config.Systemd == "always" {
+		features = append(features, "--systemd=always")
+	}
+	if len(config.Secrets) > 0 {
+		features = append(features, "--secret")
+	}
+	return features
+}
+
+// checkPodmanFeatures makes sure the installed podman supports everything
+// config asks for.
+func checkPodmanFeatures(driver Driver, config *Config) error {
+	features := requiredPodmanFeatures(config)
+	if len(features) == 0 {
+		return nil
+	}
+
+	v, err := driver.PodmanVersion()
+	if err != nil {
+		return err
+	}
+
+	for _, feature := range features {
+		if err := v.Require(feature); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package podman
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-version"
+)
+
+func TestParsePodmanVersion(t *testing.T) {
+	output := `{
+  "Client": {
+    "APIVersion": "4.9.3",
+    "Version": "4.9.3",
+    "GoVersion": "go1.22.2",
+    "OsArch": "linux/amd64"
+  }
+}`
+
+	v, err := parsePodmanVersion([]byte(output))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.Client.String() != "4.9.3" {
+		t.Fatalf("bad: %s", v.Client)
+	}
+	if v.Server != nil {
+		t.Fatalf("bad: %s", v.Server)
+	}
+	if v.APIVersion != "4.9.3" {
+		t.Fatalf("bad: %s", v.APIVersion)
+	}
+}
+
+func TestParsePodmanVersion_remote(t *testing.T) {
+	output := `{
+  "Client": {"APIVersion": "4.9.3", "Version": "4.9.3"},
+  "Server": {"APIVersion": "4.3.1", "Version": "4.3.1"}
+}`
+
+	v, err := parsePodmanVersion([]byte(output))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.Server == nil || v.Server.String() != "4.3.1" {
+		t.Fatalf("bad: %s", v.Server)
+	}
+}
+
+func TestParsePodmanVersion_legacy(t *testing.T) {
+	output := `{"Version": "1.9.3", "APIVersion": 1}`
+
+	v, err := parsePodmanVersion([]byte(output))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.Client.String() != "1.9.3" {
+		t.Fatalf("bad: %s", v.Client)
+	}
+	if v.APIVersion != "1" {
+		t.Fatalf("bad: %s", v.APIVersion)
+	}
+}
+
+func TestParsePodmanVersion_error(t *testing.T) {
+	for _, output := range []string{"podman version 4.9.3", `{"Client": {}}`} {
+		if _, err := parsePodmanVersion([]byte(output)); err == nil {
+			t.Fatalf("should error: %s", output)
+		}
+	}
+}
+
+func TestPodmanVersionRequire(t *testing.T) {
+	v := &PodmanVersion{Client: version.Must(version.NewVersion("1.9.3"))}
+	if err := v.Require("--systemd=always"); err == nil {
+		t.Fatal("should error")
+	}
+
+	v.Client = version.Must(version.NewVersion("2.0.0"))
+	if err := v.Require("--systemd=always"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The remote service is the one running the containers
+	v.Server = version.Must(version.NewVersion("1.9.3"))
+	if err := v.Require("--systemd=always"); err == nil {
+		t.Fatal("should error")
+	}
+
+	if err := v.Require("--unknown"); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,98 @@
+//go:generate packer-sdc struct-markdown
+
+package podman
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// RegistryAuth holds the credentials used for a single registry. The
+// credentials are written to a temporary auth file that is passed to podman
+// with `--authfile`, so the auth file of the user is never touched.
+//
+// Instead of static credentials, a command can be given to fetch short lived
+// ones, for example from a cloud token helper. The command must print a JSON
+// object with `username`, `token` and optionally an RFC 3339 `expires_at`:
+//
+// ```json
+// {"username": "AWS", "token": "...", "expires_at": "2021-01-01T00:00:00Z"}
+// ```
+//
+// The credentials are cached until they expire.
+type RegistryAuth struct {
+	// The registry the credentials are for, for example `quay.io`.
+	Server string `mapstructure:"server" required:"true"`
+	// The username used to authenticate to the registry.
+	Username string `mapstructure:"username" required:"false"`
+	// The password used to authenticate to the registry.
+	Password string `mapstructure:"password" required:"false"`
+	// A command, and its arguments, printing the credentials used to
+	// authenticate to the registry. This can't be combined with `username`
+	// and `password`. Example: `["sh", "-c", "ecr-token-helper | jq ..."]`
+	Command []string `mapstructure:"command" required:"false"`
+}
+
+func (r *RegistryAuth) Prepare() []error {
+	if r.Password != "" {
+		packersdk.LogSecretFilter.Set(r.Password)
+	}
+
+	var errs []error
+	if r.Server == "" {
+		errs = append(errs, fmt.Errorf("registry_auth: server must be specified"))
+	}
+	if len(r.Command) > 0 {
+		if r.Username != "" || r.Password != "" {
+			errs = append(errs, fmt.Errorf("registry_auth: command can't be combined with username and password for %q", r.Server))
+		}
+	} else if r.Username == "" || r.Password == "" {
+		errs = append(errs, fmt.Errorf("registry_auth: username and password or command must be specified for %q", r.Server))
+	}
+	return errs
+}
+
+// credentials returns the username and password for the registry, running
+// the credential command if there is one.
+func (r *RegistryAuth) credentials() (string, string, error) {
+	if len(r.Command) == 0 {
+		return r.Username, r.Password, nil
+	}
+
+	username, token, err := runCredentialCommand(r.Command)
+	if err != nil {
+		return "", "", fmt.Errorf("registry_auth %q: %s", r.Server, err)
+	}
+	packersdk.LogSecretFilter.Set(token)
+	return username, token, nil
+}
+
+// writeAuthFile writes the given credentials to path, in the auth.json
+// format understood by podman.
+func writeAuthFile(path string, auths []RegistryAuth) error {
+	type authEntry struct {
+		Auth string `json:"auth"`
+	}
+
+	entries := make(map[string]authEntry, len(auths))
+	for _, a := range auths {
+		username, password, err := a.credentials()
+		if err != nil {
+			return err
+		}
+		entries[a.Server] = authEntry{
+			Auth: base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+		}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"auths": entries})
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
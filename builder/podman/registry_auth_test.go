@@ -0,0 +1,97 @@
+package podman
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestRegistryAuthPrepare(t *testing.T) {
+	r := RegistryAuth{Server: "quay.io", Username: "foo", Password: "bar"}
+	if errs := r.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// The password is scrubbed from the output
+	r = RegistryAuth{Server: "quay.io", Username: "foo", Password: "registry-hunter2"}
+	r.Prepare()
+	if out := packersdk.LogSecretFilter.FilterString("pull with registry-hunter2"); strings.Contains(out, "hunter2") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	r = RegistryAuth{Username: "foo", Password: "bar"}
+	if errs := r.Prepare(); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	r = RegistryAuth{Server: "quay.io", Username: "foo"}
+	if errs := r.Prepare(); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	r = RegistryAuth{Server: "quay.io", Command: []string{"helper"}}
+	if errs := r.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	r = RegistryAuth{Server: "quay.io", Command: []string{"helper"}, Password: "bar"}
+	if errs := r.Prepare(); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
+func TestWriteAuthFile(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "auth.json")
+	err = writeAuthFile(path, []RegistryAuth{
+		{Server: "quay.io", Username: "foo", Password: "bar"},
+		{Server: "ghcr.io", Username: "baz", Password: "qux"},
+		{Server: "ecr.aws", Command: []string{"echo", `{"username": "AWS", "token": "hunter2"}`}},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("bad mode: %s", fi.Mode())
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var authFile struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(contents, &authFile); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// base64 of foo:bar, baz:qux and AWS:hunter2
+	if authFile.Auths["quay.io"].Auth != "Zm9vOmJhcg==" {
+		t.Fatalf("bad: %#v", authFile.Auths)
+	}
+	if authFile.Auths["ghcr.io"].Auth != "YmF6OnF1eA==" {
+		t.Fatalf("bad: %#v", authFile.Auths)
+	}
+	if authFile.Auths["ecr.aws"].Auth != "QVdTOmh1bnRlcjI=" {
+		t.Fatalf("bad: %#v", authFile.Auths)
+	}
+}
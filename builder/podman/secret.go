@@ -0,0 +1,143 @@
+//go:generate packer-sdc struct-markdown
+
+package podman
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// minSecretScanLength is the length under which a secret isn't looked for
+// in the container filesystem, or in the output, since it would be found by
+// chance.
+const minSecretScanLength = 8
+
+// SecretConfig describes a secret, such as the credentials of a package
+// mirror, made available to the provisioners at `/run/secrets/<name>`. The
+// secret is created with `podman secret create`, removed after the build, and
+// never committed nor exported.
+type SecretConfig struct {
+	// The name of the secret, which is also the name of its file in
+	// `/run/secrets`.
+	Name string `mapstructure:"name" required:"true"`
+	// Path to the file holding the secret. This can't be combined with
+	// `env`.
+	File string `mapstructure:"file" required:"false"`
+	// The environment variable holding the secret. This can't be combined
+	// with `file`.
+	Env string `mapstructure:"env" required:"false"`
+
+	value []byte
+}
+
+func (s *SecretConfig) Prepare() []error {
+	if !nameRegexp.MatchString(s.Name) {
+		return []error{fmt.Errorf("secret: invalid name %q, it must be a valid file name", s.Name)}
+	}
+
+	switch {
+	case s.File != "" && s.Env != "":
+		return []error{fmt.Errorf("secret %q: file and env can't be combined", s.Name)}
+	case s.File != "":
+		value, err := ioutil.ReadFile(s.File)
+		if err != nil {
+			return []error{fmt.Errorf("secret %q: error reading file: %s", s.Name, err)}
+		}
+		s.value = value
+	case s.Env != "":
+		value, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return []error{fmt.Errorf("secret %q: environment variable %s isn't set", s.Name, s.Env)}
+		}
+		s.value = []byte(value)
+	default:
+		return []error{fmt.Errorf("secret %q: one of file or env must be specified", s.Name)}
+	}
+
+	if len(s.value) == 0 {
+		return []error{fmt.Errorf("secret %q: the secret is empty", s.Name)}
+	}
+
+	// Output is filtered line by line, so the lines of multi-line secrets,
+	// such as certificates, are scrubbed on their own too.
+	packersdk.LogSecretFilter.Set(strings.TrimSpace(string(s.value)))
+	for _, line := range strings.Split(string(s.value), "\n") {
+		if line = strings.TrimSpace(line); len(line) >= minSecretScanLength {
+			packersdk.LogSecretFilter.Set(line)
+		}
+	}
+
+	return nil
+}
+
+// findSecrets reads a tar stream of a container filesystem and returns the
+// paths of the files holding any of the given secrets, by secret name.
+func findSecrets(r io.Reader, secrets []SecretConfig) (map[string][]string, error) {
+	var needles []SecretConfig
+	longest := 0
+	for _, secret := range secrets {
+		value := bytes.TrimSpace(secret.value)
+		if len(value) < minSecretScanLength {
+			continue
+		}
+		needles = append(needles, SecretConfig{Name: secret.Name, value: value})
+		if len(value) > longest {
+			longest = len(value)
+		}
+	}
+
+	found := make(map[string][]string)
+	if len(needles) == 0 {
+		return found, nil
+	}
+
+	tr := tar.NewReader(r)
+	buf := make([]byte, 32*1024+longest)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return found, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Keep the tail of the previous chunk so that secrets spanning two
+		// chunks are found too.
+		matched := make(map[string]bool)
+		kept := 0
+		for {
+			n, err := tr.Read(buf[kept:])
+			window := buf[:kept+n]
+			for _, needle := range needles {
+				if !matched[needle.Name] && bytes.Contains(window, needle.value) {
+					matched[needle.Name] = true
+					found[needle.Name] = append(found[needle.Name], path.Join("/", hdr.Name))
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			kept = longest - 1
+			if kept > len(window) {
+				kept = len(window)
+			}
+			copy(buf, window[len(window)-kept:])
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package podman
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestSecretConfigPrepare_file(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	if _, err := tf.WriteString("machine mirror.example.com\npassword hunter2-mirror\n"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+
+	s := SecretConfig{Name: "netrc", File: tf.Name()}
+	if errs := s.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Both the secret and its lines are scrubbed from the output
+	out := packersdk.LogSecretFilter.FilterString("login with password hunter2-mirror")
+	if strings.Contains(out, "hunter2-mirror") {
+		t.Fatalf("secret not filtered: %s", out)
+	}
+}
+
+func TestSecretConfigPrepare_empty(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Close()
+
+	s := SecretConfig{Name: "netrc", File: tf.Name()}
+	if errs := s.Prepare(); len(errs) == 0 {
+		t.Fatal("should error")
+	}
+}
+
+func testSecretsTar(t *testing.T, files map[string][]byte) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return &buf
+}
+
+func TestFindSecrets(t *testing.T) {
+	secrets := []SecretConfig{
+		{Name: "token", value: []byte("s3cr3t-token\n")},
+		{Name: "short", value: []byte("abc")},
+	}
+
+	// The token straddles the first two chunks read from the large file
+	large := bytes.Repeat([]byte("x"), 32*1024+len("s3cr3t-token")-6)
+	large = append(large, []byte("s3cr3t-token")...)
+	large = append(large, bytes.Repeat([]byte("x"), 1024)...)
+
+	r := testSecretsTar(t, map[string][]byte{
+		"etc/motd":        []byte("abc is too short to be looked for"),
+		"root/.netrc":     []byte("password s3cr3t-token"),
+		"./var/log/large": large,
+		"etc/hostname":    []byte("s3cr3t"),
+	})
+
+	found, err := findSecrets(r, secrets)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(found) != 1 {
+		t.Fatalf("bad: %#v", found)
+	}
+	paths := found["token"]
+	if len(paths) != 2 {
+		t.Fatalf("bad: %#v", paths)
+	}
+	for _, p := range []string{"/root/.netrc", "/var/log/large"} {
+		if paths[0] != p && paths[1] != p {
+			t.Fatalf("%s not found: %#v", p, paths)
+		}
+	}
+}
+
+func TestFindSecrets_none(t *testing.T) {
+	secrets := []SecretConfig{{Name: "token", value: []byte("s3cr3t-token")}}
+	r := testSecretsTar(t, map[string][]byte{"etc/hostname": []byte("build")})
+
+	found, err := findSecrets(r, secrets)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(found, map[string][]string{}) {
+		t.Fatalf("bad: %#v", found)
+	}
+}
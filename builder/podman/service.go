@@ -0,0 +1,58 @@
+//go:generate packer-sdc struct-markdown
+
+package podman
+
+import (
+	"fmt"
+	"time"
+)
+
+// ServiceConfig describes a service container, such as a database, started
+// next to the build container while provisioning. The build container and its
+// services share a pod, and thus a network namespace: services are reachable
+// from the build container on `localhost`, or by their name. Services are
+// never committed nor exported.
+type ServiceConfig struct {
+	// The name of the service, which the build container can also use as
+	// its host name.
+	Name string `mapstructure:"name" required:"true"`
+	// The image the service is started from.
+	Image string `mapstructure:"image" required:"true"`
+	// Environment variables to set in the service container.
+	Env map[string]string `mapstructure:"env" required:"false"`
+	// The command to run in the service container, instead of the default
+	// command of the image.
+	Command []string `mapstructure:"command" required:"false"`
+	// Ports to publish on the host, in the `podman run --publish` format,
+	// for example `5432:5432`.
+	Ports []string `mapstructure:"ports" required:"false"`
+	// A shell command run in the service container to tell whether it is
+	// healthy, for example `pg_isready`. When set, provisioning only starts
+	// once it succeeds.
+	HealthCommand string `mapstructure:"health_command" required:"false"`
+	// How long to wait between two runs of `health_command`. Defaults to
+	// `1s`.
+	HealthInterval time.Duration `mapstructure:"health_interval" required:"false"`
+	// How long to wait for the service to become healthy before failing
+	// the build. Defaults to `1m`.
+	HealthTimeout time.Duration `mapstructure:"health_timeout" required:"false"`
+}
+
+func (s *ServiceConfig) Prepare() []error {
+	var errs []error
+	if !nameRegexp.MatchString(s.Name) {
+		errs = append(errs, fmt.Errorf("service: invalid name %q, it must be a valid host name", s.Name))
+	}
+	if s.Image == "" {
+		errs = append(errs, fmt.Errorf("service %q: image must be specified", s.Name))
+	}
+
+	if s.HealthInterval == 0 {
+		s.HealthInterval = time.Second
+	}
+	if s.HealthTimeout == 0 {
+		s.HealthTimeout = time.Minute
+	}
+
+	return errs
+}
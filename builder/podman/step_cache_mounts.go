@@ -0,0 +1,77 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepCacheMounts prunes the stale cache volumes of the build, and creates
+// the missing ones. Cache volumes outlive the build, so there is nothing to
+// clean up.
+type StepCacheMounts struct{}
+
+func (s *StepCacheMounts) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	config, ok := state.Get("config").(*Config)
+	if !ok {
+		err := fmt.Errorf("error encountered obtaining podman config")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	driver := state.Get("driver").(Driver)
+	labels := cacheVolumeLabels(config.CacheScope, config.PackerBuildName)
+
+	if config.CachePruneAfter > 0 {
+		volumes, err := driver.ListVolumes(labels)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		// A volume still in use by another build can't be removed, which
+		// doesn't prevent this one from running.
+		for _, name := range staleVolumes(volumes, time.Now().Add(-config.CachePruneAfter)) {
+			ui.Say(fmt.Sprintf("Removing cache volume %s, older than %s", name, config.CachePruneAfter))
+			if err := driver.RemoveVolume(name); err != nil {
+				ui.Error(fmt.Sprintf("Error removing the cache volume: %s", err))
+			}
+		}
+	}
+
+	names := make([]string, 0, len(config.CacheMounts))
+	for name := range config.CacheMounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		exists, err := driver.VolumeExists(name)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if exists {
+			continue
+		}
+
+		ui.Say(fmt.Sprintf("Creating cache volume %s", name))
+		if err := driver.CreateVolume(name, labels); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepCacheMounts) Cleanup(state multistep.StateBag) {}
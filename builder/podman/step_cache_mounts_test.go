@@ -0,0 +1,126 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func testStepCacheMountsState(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.PackerBuildName = "podman.example"
+	config.CacheScope = "/templates/example"
+	config.CacheMounts = map[string]string{
+		"npm-cache": "/root/.npm",
+		"dnf-cache": "/var/cache/dnf",
+	}
+	return state
+}
+
+func TestStepCacheMounts_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCacheMounts)
+}
+
+func TestStepCacheMounts(t *testing.T) {
+	state := testStepCacheMountsState(t)
+	step := new(StepCacheMounts)
+	defer step.Cleanup(state)
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the missing volumes were created with the labels of the build
+	driver := state.Get("driver").(*MockDriver)
+	if strings.Join(driver.CreateVolumeNames, " ") != "dnf-cache npm-cache" {
+		t.Fatalf("bad: %#v", driver.CreateVolumeNames)
+	}
+	if driver.CreateVolumeLabels[cacheVolumeLabel] != "podman.example" {
+		t.Fatalf("bad: %#v", driver.CreateVolumeLabels)
+	}
+	if driver.CreateVolumeLabels[cacheScopeLabel] != cacheVolumeLabels("/templates/example", "")[cacheScopeLabel] {
+		t.Fatalf("bad: %#v", driver.CreateVolumeLabels)
+	}
+
+	// verify nothing was pruned
+	if driver.ListVolumesCalled {
+		t.Fatal("should not have listed volumes")
+	}
+}
+
+func TestStepCacheMounts_exists(t *testing.T) {
+	state := testStepCacheMountsState(t)
+	step := new(StepCacheMounts)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.VolumeExistsResult = true
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the volumes are reused
+	if len(driver.VolumeExistsNames) != 2 {
+		t.Fatalf("bad: %#v", driver.VolumeExistsNames)
+	}
+	if len(driver.CreateVolumeNames) != 0 {
+		t.Fatalf("bad: %#v", driver.CreateVolumeNames)
+	}
+}
+
+func TestStepCacheMounts_prune(t *testing.T) {
+	state := testStepCacheMountsState(t)
+	step := new(StepCacheMounts)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.CachePruneAfter = 24 * time.Hour
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ListVolumesResult = []Volume{
+		{Name: "dnf-cache", CreatedAt: time.Now().Add(-48 * time.Hour)},
+		{Name: "npm-cache", CreatedAt: time.Now().Add(-time.Hour)},
+	}
+	driver.RemoveVolumeErr = errors.New("volume is in use")
+
+	// run the step, failing to remove a volume doesn't fail the build
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify only the volumes of this build were looked at
+	if driver.ListVolumesLabels[cacheVolumeLabel] != "podman.example" ||
+		driver.ListVolumesLabels[cacheScopeLabel] == "" {
+		t.Fatalf("bad: %#v", driver.ListVolumesLabels)
+	}
+	if strings.Join(driver.RemoveVolumeNames, " ") != "dnf-cache" {
+		t.Fatalf("bad: %#v", driver.RemoveVolumeNames)
+	}
+}
+
+func TestStepCacheMounts_error(t *testing.T) {
+	state := testStepCacheMountsState(t)
+	step := new(StepCacheMounts)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.CreateVolumeErr = errors.New("foo")
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we have an error
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
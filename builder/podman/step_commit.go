@@ -26,7 +26,7 @@ func (s *StepCommit) Run(ctx context.Context, state multistep.StateBag) multiste
 	driver := state.Get("driver").(Driver)
 	containerId := state.Get("container_id").(string)
 	ui.Say("Committing the container")
-	imageId, err := driver.Commit(containerId, config.Author, config.Changes, config.Message)
+	imageId, err := driver.Commit(containerId, config.Author, config.Changes, config.Message, config.CommitPause)
 	if err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
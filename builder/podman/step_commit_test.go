@@ -35,6 +35,9 @@ func TestStepCommit(t *testing.T) {
 	if !driver.CommitCalled {
 		t.Fatal("should've called")
 	}
+	if !driver.CommitPause {
+		t.Fatal("should pause by default")
+	}
 
 	// verify the ID is saved
 	idRaw, ok := state.GetOk("image_id")
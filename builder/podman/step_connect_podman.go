@@ -3,8 +3,6 @@ package podman
 import (
 	"context"
 	"fmt"
-	"os/exec"
-	"strings"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 )
@@ -30,12 +28,18 @@ func (s *StepConnectPodman) Run(ctx context.Context, state multistep.StateBag) m
 		return multistep.ActionHalt
 	}
 
-	containerUser, err := getContainerUser(containerId)
+	info, err := driver.PodmanInfo()
 	if err != nil {
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
 
+	container, err := driver.InspectContainer(containerId)
+	if err != nil {
+		state.Put("error", fmt.Errorf("Failed to inspect the container: %s", err))
+		return multistep.ActionHalt
+	}
+
 	// Create the communicator that talks to Podman via various
 	// os/exec tricks.
 	comm := &Communicator{
@@ -44,7 +48,8 @@ func (s *StepConnectPodman) Run(ctx context.Context, state multistep.StateBag) m
 		ContainerDir:  config.ContainerDir,
 		Version:       version,
 		Config:        config,
-		ContainerUser: containerUser,
+		ContainerUser: container.Config.User,
+		Rootless:      info.Rootless,
 		EntryPoint:    []string{"/bin/sh", "-c"},
 	}
 	state.Put("communicator", comm)
@@ -52,16 +57,3 @@ func (s *StepConnectPodman) Run(ctx context.Context, state multistep.StateBag) m
 }
 
 func (s *StepConnectPodman) Cleanup(state multistep.StateBag) {}
-
-func getContainerUser(containerId string) (string, error) {
-	inspectArgs := []string{"podman", "inspect", "--format", "{{.Config.User}}", containerId}
-	stdout, err := exec.Command(inspectArgs[0], inspectArgs[1:]...).Output()
-	if err != nil {
-		errStr := fmt.Sprintf("Failed to inspect the container: %s", err)
-		if ee, ok := err.(*exec.ExitError); ok {
-			errStr = fmt.Sprintf("%s, %s", errStr, ee.Stderr)
-		}
-		return "", fmt.Errorf(errStr)
-	}
-	return strings.TrimSpace(string(stdout)), nil
-}
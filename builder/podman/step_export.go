@@ -1,16 +1,21 @@
 package podman
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
-// StepExport exports the container to a flat tar file.
+// StepExport exports the container to a file, by default a flat tar file.
 type StepExport struct{}
 
 func (s *StepExport) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -38,30 +43,123 @@ func (s *StepExport) Run(ctx context.Context, state multistep.StateBag) multiste
 		return multistep.ActionHalt
 	}
 
-	// Open the file that we're going to write to
-	f, err := os.Create(config.ExportPath)
-	if err != nil {
-		err := fmt.Errorf("Error creating output file: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
-	}
-
 	driver := state.Get("driver").(Driver)
 	containerId := state.Get("container_id").(string)
 
-	ui.Say("Exporting the container")
-	if err := driver.Export(containerId, f); err != nil {
-		f.Close()
-		os.Remove(f.Name())
+	ui.Say(fmt.Sprintf("Exporting the container as %s", config.ExportFormat))
 
+	var err error
+	if config.ExportFormat == "squashfs" {
+		err = exportSquashfs(driver, containerId, config)
+	} else {
+		err = exportArchive(driver, containerId, config)
+	}
+	if err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
-	f.Close()
 	return multistep.ActionContinue
 }
 
 func (s *StepExport) Cleanup(state multistep.StateBag) {}
+
+// exportArchive writes the container to config.ExportPath as a tar stream,
+// either the flat filesystem or an OCI image layout, optionally compressed.
+// The file is removed on error, so that a truncated archive isn't mistaken
+// for a real one.
+func exportArchive(driver Driver, containerId string, config *Config) (err error) {
+	// Open the file that we're going to write to
+	f, err := os.Create(config.ExportPath)
+	if err != nil {
+		return fmt.Errorf("Error creating output file: %s", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("Error writing output file: %s", closeErr)
+		}
+		if err != nil {
+			os.Remove(config.ExportPath)
+		}
+	}()
+
+	w, err := newCompressWriter(f, config.ExportCompression, config.ExportCompressionLevel)
+	if err != nil {
+		return err
+	}
+
+	// The compress writer is closed on errors too, to release its encoder
+	err = writeArchive(driver, containerId, config, w)
+	if closeErr := w.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("Error compressing output file: %s", closeErr)
+	}
+	return err
+}
+
+// writeArchive writes the tar stream exportArchive exports to w.
+func writeArchive(driver Driver, containerId string, config *Config, w io.Writer) error {
+	if config.ExportFormat != "oci-layout" {
+		return driver.Export(containerId, w)
+	}
+
+	// An image layout can only be produced from an image, so commit the
+	// container to a throwaway image first.
+	imageId, err := driver.Commit(containerId, config.Author, config.Changes, config.Message, config.CommitPause)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := driver.DeleteImage(imageId); err != nil {
+			log.Printf("[WARN] Error deleting temporary image %s: %s", imageId, err)
+		}
+	}()
+
+	return driver.SaveImage(imageId, "oci-archive", w)
+}
+
+// exportSquashfs streams the container filesystem into sqfstar, which builds
+// a squashfs image at config.ExportPath.
+func exportSquashfs(driver Driver, containerId string, config *Config) error {
+	var args []string
+	if config.ExportCompression != "" {
+		args = append(args, "-comp", config.ExportCompression)
+	}
+	if config.ExportCompressionLevel != 0 {
+		args = append(args, "-Xcompression-level", strconv.Itoa(config.ExportCompressionLevel))
+	}
+	args = append(args, config.ExportPath)
+
+	// Never build on top of the image left behind by a previous export
+	if err := os.Remove(config.ExportPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("sqfstar", args...)
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Creating squashfs image with args: %v", args)
+	if err := cmd.Start(); err != nil {
+		stdin.Close()
+		return fmt.Errorf("Error running sqfstar: %s", err)
+	}
+
+	exportErr := driver.Export(containerId, stdin)
+	stdin.Close()
+
+	if err := cmd.Wait(); err != nil {
+		os.Remove(config.ExportPath)
+		return fmt.Errorf("Error creating squashfs image: %s\nStderr: %s",
+			err, stderr.String())
+	}
+	if exportErr != nil {
+		os.Remove(config.ExportPath)
+	}
+
+	return exportErr
+}
@@ -2,13 +2,19 @@ package podman
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"errors"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
 func testStepExportState(t *testing.T) multistep.StateBag {
@@ -99,3 +105,281 @@ func TestStepExport_error(t *testing.T) {
 		t.Fatal("export path shouldn't exist")
 	}
 }
+
+func TestStepExport_compression(t *testing.T) {
+	state := testStepExportState(t)
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	// Create a tempfile for our output path
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	config := state.Get("config").(*Config)
+	config.ExportPath = tf.Name()
+	config.ExportCompression = "gzip"
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExportReader = bytes.NewReader([]byte("data!"))
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the data exported to the file is compressed
+	f, err := os.Open(tf.Name())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if string(contents) != "data!" {
+		t.Fatalf("bad: %#v", string(contents))
+	}
+}
+
+func TestStepExport_ociLayout(t *testing.T) {
+	state := testStepExportState(t)
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	// Create a tempfile for our output path
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	config := state.Get("config").(*Config)
+	config.ExportPath = tf.Name()
+	config.ExportFormat = "oci-layout"
+	driver := state.Get("driver").(*MockDriver)
+	driver.CommitImageId = "bar"
+	driver.SaveImageReader = bytes.NewReader([]byte("layout!"))
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we saved a temporary image instead of exporting the container
+	if driver.ExportCalled {
+		t.Fatal("shouldn't have exported")
+	}
+	if !driver.CommitCalled || driver.CommitContainerId != "foo" {
+		t.Fatal("should've committed the container")
+	}
+	if driver.SaveImageId != "bar" || driver.SaveImageFormat != "oci-archive" {
+		t.Fatalf("bad: %#v %#v", driver.SaveImageId, driver.SaveImageFormat)
+	}
+	if !driver.DeleteImageCalled || driver.DeleteImageId != "bar" {
+		t.Fatal("should've deleted the temporary image")
+	}
+
+	contents, err := ioutil.ReadFile(tf.Name())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if string(contents) != "layout!" {
+		t.Fatalf("bad: %#v", string(contents))
+	}
+}
+
+func TestStepExport_ociLayoutError(t *testing.T) {
+	state := testStepExportState(t)
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	// Create a tempfile for our output path
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	config := state.Get("config").(*Config)
+	config.ExportPath = tf.Name()
+	config.ExportFormat = "oci-layout"
+	config.ExportCompression = "zstd"
+	driver := state.Get("driver").(*MockDriver)
+	driver.CommitErr = errors.New("foo")
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the truncated archive isn't left behind
+	if _, err := os.Stat(tf.Name()); !os.IsNotExist(err) {
+		t.Fatalf("export path shouldn't exist: %s", err)
+	}
+}
+
+func TestStepExport_compressionZstdXz(t *testing.T) {
+	readers := map[string]func(io.Reader) (io.Reader, error){
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		"xz":   func(r io.Reader) (io.Reader, error) { return xz.NewReader(r) },
+	}
+
+	for algorithm, newReader := range readers {
+		for _, level := range []int{0, 1, 9} {
+			state := testStepExportState(t)
+			step := new(StepExport)
+
+			// Create a tempfile for our output path
+			tf, err := ioutil.TempFile("", "packer")
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			tf.Close()
+			defer os.Remove(tf.Name())
+
+			config := state.Get("config").(*Config)
+			config.ExportPath = tf.Name()
+			config.ExportCompression = algorithm
+			config.ExportCompressionLevel = level
+			driver := state.Get("driver").(*MockDriver)
+			driver.ExportReader = bytes.NewReader([]byte("data!"))
+
+			// run the step
+			if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+				t.Fatalf("%s %d: bad action: %#v", algorithm, level, action)
+			}
+
+			// verify the data exported to the file is compressed
+			f, err := os.Open(tf.Name())
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			r, err := newReader(f)
+			if err != nil {
+				t.Fatalf("%s %d: err: %s", algorithm, level, err)
+			}
+			contents, err := ioutil.ReadAll(r)
+			f.Close()
+			if err != nil {
+				t.Fatalf("%s %d: err: %s", algorithm, level, err)
+			}
+
+			if string(contents) != "data!" {
+				t.Fatalf("%s %d: bad: %#v", algorithm, level, string(contents))
+			}
+		}
+	}
+}
+
+// testFakeSqfstar puts a sqfstar in PATH that records its arguments next to
+// the image it writes, which is a copy of its input.
+func testFakeSqfstar(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	script := "#!/bin/sh\n" +
+		"for out; do :; done\n" +
+		"echo \"$@\" > \"$out.args\"\n" +
+		"cat > \"$out\"\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "sqfstar"), []byte(script), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	return dir, func() {
+		os.Setenv("PATH", path)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStepExport_squashfs(t *testing.T) {
+	dir, cleanup := testFakeSqfstar(t)
+	defer cleanup()
+
+	state := testStepExportState(t)
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ExportPath = filepath.Join(dir, "rootfs.sqfs")
+	config.ExportFormat = "squashfs"
+	config.ExportCompression = "zstd"
+	config.ExportCompressionLevel = 19
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExportReader = bytes.NewReader([]byte("data!"))
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the container was streamed to sqfstar
+	contents, err := ioutil.ReadFile(config.ExportPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(contents) != "data!" {
+		t.Fatalf("bad: %#v", string(contents))
+	}
+	args, err := ioutil.ReadFile(config.ExportPath + ".args")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := "-comp zstd -Xcompression-level 19 " + config.ExportPath
+	if strings.TrimSpace(string(args)) != expected {
+		t.Fatalf("bad: %s", args)
+	}
+}
+
+func TestStepExport_squashfsError(t *testing.T) {
+	dir, cleanup := testFakeSqfstar(t)
+	defer cleanup()
+
+	state := testStepExportState(t)
+	step := new(StepExport)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ExportPath = filepath.Join(dir, "rootfs.sqfs")
+	config.ExportFormat = "squashfs"
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExportReader = bytes.NewReader([]byte("data!"))
+	driver.ExportError = errors.New("foo")
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the partial image isn't left behind
+	if _, err := os.Stat(config.ExportPath); !os.IsNotExist(err) {
+		t.Fatalf("export path shouldn't exist: %s", err)
+	}
+
+	// without sqfstar
+	os.Setenv("PATH", "")
+	state.Remove("error")
+	driver.ExportCalled = false
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.ExportCalled {
+		t.Fatal("shouldn't have exported")
+	}
+}
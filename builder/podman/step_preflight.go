@@ -0,0 +1,392 @@
+package podman
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// resourceLimitFlags are the run_command flags that need cgroup v2 when
+// podman runs rootless.
+var resourceLimitFlags = []string{
+	"--blkio-weight", "--cpu-period", "--cpu-quota", "--cpu-shares", "--cpus",
+	"--cpuset-cpus", "--cpuset-mems", "--memory", "-m", "--memory-reservation",
+	"--memory-swap", "--pids-limit",
+}
+
+// StepPreflight checks the podman host before anything is pulled or
+// started, so that all the problems are reported at once and early.
+type StepPreflight struct {
+	// pingRegistry checks that a registry can be reached. It defaults to
+	// pingRegistry below and is only replaced in tests.
+	pingRegistry func(host string, tlsConfig *tls.Config) error
+}
+
+func (s *StepPreflight) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	config, ok := state.Get("config").(*Config)
+	if !ok {
+		err := fmt.Errorf("error encountered obtaining podman config")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if config.SkipPreflight {
+		log.Println("skip_preflight is set, won't check the podman host")
+		return multistep.ActionContinue
+	}
+
+	driver := state.Get("driver").(Driver)
+
+	ui.Say("Running preflight checks...")
+	info, err := driver.PodmanInfo()
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	var errs *packersdk.MultiError
+
+	if info.GraphDriverName == "vfs" {
+		ui.Message("Podman uses the vfs storage driver, which copies every layer in " +
+			"full: builds will be slow and use a lot of disk, consider overlay")
+	}
+
+	exists, err := driver.ImageExists(config.Image)
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Error looking up Podman image: %s", err))
+	} else if !exists && config.PullPolicy == "never" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+			"Image %s isn't in local storage and pull_policy is never: pull it "+
+				"first or change pull_policy", config.Image))
+	}
+
+	pullNeeded := config.PullPolicy == "always" || config.PullPolicy == "newer" ||
+		(config.PullPolicy == "missing" && !exists)
+
+	if exists {
+		if err := checkFreeSpace(driver, info, config.Image); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	} else if err == nil && pullNeeded {
+		if err := checkPullSpace(driver, info, config); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+
+	if info.Rootless && info.CgroupVersion == "v1" {
+		commits := config.Commit || config.ExportFormat == "oci-layout"
+		if config.CommitPause && commits {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"commit_pause needs cgroup v2 when podman runs rootless, "+
+					"but this host uses cgroup v1: set commit_pause to false"))
+		}
+		if config.Systemd == "always" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"systemd = \"always\" needs cgroup v2 when podman runs rootless, "+
+					"but this host uses cgroup v1"))
+		}
+		if flags := usedResourceLimitFlags(config.RunCommand); len(flags) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"resource limits (%s) need cgroup v2 when podman runs rootless, "+
+					"but this host uses cgroup v1", strings.Join(flags, ", ")))
+		}
+	}
+
+	if host := registryHost(config.Image); pullNeeded && host != "" {
+		ping := s.pingRegistry
+		if ping == nil {
+			ping = pingRegistry
+		}
+
+		// Mirrors and the other settings of registries.conf aren't taken
+		// into account, so a registry podman pulls from fine may look
+		// unreachable: only warn, the pull will tell.
+		tlsConfig, err := registryTLSConfig(host, config.CertDir, config.TLSVerify.False())
+		if err == nil {
+			err = ping(host, tlsConfig)
+		}
+		if err != nil {
+			ui.Message(fmt.Sprintf("Warning: registry %s can't be reached, pulling "+
+				"will likely fail: %s", host, err))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		state.Put("error", errs)
+		ui.Error(errs.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepPreflight) Cleanup(state multistep.StateBag) {}
+
+// checkFreeSpace makes sure there is room for at least another copy of the
+// base image in the podman storage, which is what committing it takes at
+// worst.
+func checkFreeSpace(driver Driver, info *PodmanInfo, image string) error {
+	free := info.GraphRootFree()
+	if free == 0 {
+		log.Println("[DEBUG] Podman doesn't report the free space of its storage")
+		return nil
+	}
+
+	inspect, err := driver.InspectImage(image)
+	if err != nil {
+		return fmt.Errorf("Error inspecting Podman image: %s", err)
+	}
+
+	size := inspect.Size
+	if uint64(size) > free {
+		return fmt.Errorf("Only %s are free in %s, but the base image alone takes %s: "+
+			"free up some space, for example with podman system prune",
+			formatBytes(free), info.GraphRoot, formatBytes(uint64(size)))
+	}
+
+	return nil
+}
+
+// checkPullSpace makes sure the image to pull fits in the podman storage,
+// going by the compressed size of its layers in the registry, which is less
+// than what they take once unpacked.
+func checkPullSpace(driver Driver, info *PodmanInfo, config *Config) error {
+	free := info.GraphRootFree()
+	if free == 0 {
+		log.Println("[DEBUG] Podman doesn't report the free space of its storage")
+		return nil
+	}
+
+	platform := config.PullPlatform
+	if platform == "" {
+		platform = info.OS + "/" + info.Arch
+	}
+	size, err := remoteImageSize(driver, config.Image, platform, pullOptions(config, config.AuthFile))
+	if err != nil {
+		// The registry may need credentials only set up when pulling
+		log.Printf("[DEBUG] Error finding the size of the image to pull: %s", err)
+		return nil
+	}
+
+	if size > free {
+		return fmt.Errorf("Only %s are free in %s, but the base image takes more than %s "+
+			"once pulled: free up some space, for example with podman system prune",
+			formatBytes(free), info.GraphRoot, formatBytes(size))
+	}
+
+	return nil
+}
+
+// remoteImageSize returns the compressed size of the layers of image for
+// platform, in the os/arch[/variant] form, as told by its registry.
+func remoteImageSize(driver Driver, image, platform string, options PullOptions) (uint64, error) {
+	type manifest struct {
+		Manifests []struct {
+			Digest   string
+			Platform struct {
+				OS           string
+				Architecture string
+				Variant      string
+			}
+		}
+		Layers []struct {
+			Size uint64
+		}
+	}
+
+	raw, err := driver.RemoteManifest(image, options)
+	if err != nil {
+		return 0, err
+	}
+	var m manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return 0, fmt.Errorf("Error parsing manifest: %s", err)
+	}
+
+	// Manifest lists only point to the manifest of each platform
+	if len(m.Manifests) > 0 {
+		digest := ""
+		for _, entry := range m.Manifests {
+			p := entry.Platform
+			if platform == p.OS+"/"+p.Architecture || platform == p.OS+"/"+p.Architecture+"/"+p.Variant {
+				digest = entry.Digest
+				break
+			}
+		}
+		if digest == "" {
+			return 0, fmt.Errorf("no manifest for %s", platform)
+		}
+
+		repository := strings.SplitN(image, "@", 2)[0]
+		if raw, err = driver.RemoteManifest(pinImageDigest(repository, digest), options); err != nil {
+			return 0, err
+		}
+		m = manifest{}
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return 0, fmt.Errorf("Error parsing manifest: %s", err)
+		}
+	}
+
+	var size uint64
+	for _, layer := range m.Layers {
+		size += layer.Size
+	}
+	return size, nil
+}
+
+// usedResourceLimitFlags returns the resource limit flags in args.
+func usedResourceLimitFlags(args []string) []string {
+	var used []string
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		for _, flag := range resourceLimitFlags {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") {
+				used = append(used, flag)
+			}
+		}
+	}
+	return used
+}
+
+// registryHost returns the registry image is pulled from, or an empty
+// string for short names, which podman resolves through its search
+// registries.
+func registryHost(image string) string {
+	i := strings.Index(image, "/")
+	if i == -1 {
+		return ""
+	}
+
+	host := image[:i]
+	switch {
+	case host == "docker.io":
+		return "registry-1.docker.io"
+	case host == "localhost" || strings.ContainsAny(host, ".:"):
+		return host
+	default:
+		return ""
+	}
+}
+
+// registryCertDirs returns the directories podman looks for the
+// certificates of a registry in: cert_dir when it is set, or the certs.d
+// directories of the registry otherwise.
+func registryCertDirs(host, certDir string) []string {
+	if certDir != "" {
+		return []string{certDir}
+	}
+
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config/containers/certs.d", host))
+	}
+	return append(dirs,
+		filepath.Join("/etc/containers/certs.d", host),
+		filepath.Join("/etc/docker/certs.d", host))
+}
+
+// registryTLSConfig returns the TLS configuration podman connects to a
+// registry with: the system CAs along with the `*.crt` files of its
+// certificate directory, and the `*.cert`/`*.key` client certificates.
+func registryTLSConfig(host, certDir string, insecure bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+
+	for _, dir := range registryCertDirs(host, certDir) {
+		entries, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			switch filepath.Ext(path) {
+			case ".crt":
+				if tlsConfig.RootCAs == nil {
+					if tlsConfig.RootCAs, err = x509.SystemCertPool(); err != nil {
+						tlsConfig.RootCAs = x509.NewCertPool()
+					}
+				}
+				pem, err := ioutil.ReadFile(path)
+				if err != nil {
+					return nil, err
+				}
+				if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+					return nil, fmt.Errorf("no certificate found in %s", path)
+				}
+			case ".cert":
+				cert, err := tls.LoadX509KeyPair(path, strings.TrimSuffix(path, ".cert")+".key")
+				if err != nil {
+					return nil, err
+				}
+				tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+			}
+		}
+
+		// Like podman, only use the first directory that exists
+		break
+	}
+
+	return tlsConfig, nil
+}
+
+// pingRegistry checks that the registry API of host answers. Any answer is
+// good enough, including asking for credentials.
+func pingRegistry(host string, tlsConfig *tls.Config) error {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	resp, err := client.Get("https://" + host + "/v2/")
+	if err != nil && tlsConfig.InsecureSkipVerify {
+		// Insecure registries may not speak TLS at all
+		resp, err = client.Get("http://" + host + "/v2/")
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("registry answered %s", resp.Status)
+	}
+	return nil
+}
+
+// formatBytes formats a size in bytes for humans.
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,324 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func testStepPreflightState(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	driver := state.Get("driver").(*MockDriver)
+	driver.PodmanInfoResult = &PodmanInfo{
+		CgroupVersion:      "v2",
+		GraphDriverName:    "overlay",
+		GraphRoot:          "/var/lib/containers/storage",
+		GraphRootAllocated: 1000,
+		GraphRootUsed:      400,
+	}
+	driver.ImageExistsResult = true
+	driver.InspectImageResult = &ImageInspect{Size: 100}
+	return state
+}
+
+func testStepPreflightErrors(t *testing.T, state multistep.StateBag) []error {
+	errs, ok := state.Get("error").(*packersdk.MultiError)
+	if !ok {
+		t.Fatalf("bad: %#v", state.Get("error"))
+	}
+	return errs.Errors
+}
+
+func TestStepPreflight_impl(t *testing.T) {
+	var _ multistep.Step = new(StepPreflight)
+}
+
+func TestStepPreflight(t *testing.T) {
+	state := testStepPreflightState(t)
+	pinged := ""
+	step := &StepPreflight{pingRegistry: func(host string, tlsConfig *tls.Config) error {
+		pinged = host
+		return nil
+	}}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Image = "quay.io/foo/bar"
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we did the right thing
+	driver := state.Get("driver").(*MockDriver)
+	if !driver.PodmanInfoCalled {
+		t.Fatal("should've called podman info")
+	}
+	if driver.InspectImageImage != config.Image {
+		t.Fatalf("bad: %#v", driver.InspectImageImage)
+	}
+	if pinged != "quay.io" {
+		t.Fatalf("bad: %#v", pinged)
+	}
+}
+
+func TestStepPreflight_skip(t *testing.T) {
+	state := testStepPreflightState(t)
+	step := new(StepPreflight)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.SkipPreflight = true
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	driver := state.Get("driver").(*MockDriver)
+	if driver.PodmanInfoCalled {
+		t.Fatal("should not have called podman info")
+	}
+}
+
+func TestStepPreflight_errors(t *testing.T) {
+	state := testStepPreflightState(t)
+	step := &StepPreflight{pingRegistry: func(host string, tlsConfig *tls.Config) error {
+		return errors.New("connection refused")
+	}}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Image = "quay.io/foo/bar"
+	config.Systemd = "always"
+	config.RunCommand = []string{"-d", "--memory=1g", "--", "{{.Image}}"}
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.PodmanInfoResult.Rootless = true
+	driver.PodmanInfoResult.CgroupVersion = "v1"
+	driver.InspectImageResult.Size = 1000
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify every problem is reported at once: free space, systemd and
+	// resource limits
+	errs := testStepPreflightErrors(t, state)
+	if len(errs) != 3 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if !strings.Contains(errs[2].Error(), "--memory") {
+		t.Fatalf("bad: %s", errs[2])
+	}
+
+	// the registry may be reachable through a mirror, so it is only a
+	// warning
+	ui := state.Get("ui").(*packersdk.BasicUi)
+	if out := ui.Writer.(*bytes.Buffer).String(); !strings.Contains(out, "registry quay.io can't be reached") {
+		t.Fatalf("bad: %s", out)
+	}
+}
+
+func TestStepPreflight_commitPause(t *testing.T) {
+	state := testStepPreflightState(t)
+	step := new(StepPreflight)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Commit = true
+	config.CommitPause = true
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.PodmanInfoResult.Rootless = true
+	driver.PodmanInfoResult.CgroupVersion = "v1"
+
+	// run the step, rootless podman can't pause on cgroup v1
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	errs := testStepPreflightErrors(t, state)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "commit_pause") {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// without pausing
+	state.Remove("error")
+	config.CommitPause = false
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
+
+func TestStepPreflight_pullSpace(t *testing.T) {
+	state := testStepPreflightState(t)
+	step := &StepPreflight{pingRegistry: func(host string, tlsConfig *tls.Config) error {
+		return nil
+	}}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Image = "quay.io/foo/bar:latest"
+	config.PullPlatform = "linux/arm64"
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageExistsResult = false
+	driver.RemoteManifestResults = map[string][]byte{
+		"quay.io/foo/bar:latest": []byte(`{"manifests": [
+			{"digest": "sha256:amd64", "platform": {"os": "linux", "architecture": "amd64"}},
+			{"digest": "sha256:arm64", "platform": {"os": "linux", "architecture": "arm64"}}
+		]}`),
+		"quay.io/foo/bar@sha256:arm64": []byte(`{"layers": [{"size": 400}, {"size": 300}]}`),
+	}
+
+	// run the step, the layers of the arm64 image don't fit in 600 bytes
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	errs := testStepPreflightErrors(t, state)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "700 B") {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// the amd64 image does
+	state.Remove("error")
+	config.PullPlatform = "linux/amd64"
+	driver.RemoteManifestResults["quay.io/foo/bar@sha256:amd64"] = []byte(`{"layers": [{"size": 500}]}`)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// the size can't always be told before logging in
+	driver.RemoteManifestErr = errors.New("unauthorized")
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
+
+func TestStepPreflight_neverMissing(t *testing.T) {
+	state := testStepPreflightState(t)
+	step := new(StepPreflight)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.PullPolicy = "never"
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageExistsResult = false
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if errs := testStepPreflightErrors(t, state); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
+func TestStepPreflight_newerOffline(t *testing.T) {
+	state := testStepPreflightState(t)
+	step := &StepPreflight{pingRegistry: func(host string, tlsConfig *tls.Config) error {
+		return errors.New("connection refused")
+	}}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Image = "quay.io/foo/bar"
+	config.PullPolicy = "newer"
+
+	// run the step, the local image is good enough
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
+
+func TestPingRegistry_certDir(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	certDir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(certDir)
+
+	// without the CA of the registry
+	tlsConfig, err := registryTLSConfig(host, certDir, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := pingRegistry(host, tlsConfig); err == nil {
+		t.Fatal("should fail to verify the certificate")
+	}
+
+	// with the CA of the registry in cert_dir
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := ioutil.WriteFile(filepath.Join(certDir, "ca.crt"), ca, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tlsConfig, err = registryTLSConfig(host, certDir, false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := pingRegistry(host, tlsConfig); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestRegistryHost(t *testing.T) {
+	cases := map[string]string{
+		"ubuntu":                       "",
+		"library/ubuntu":               "",
+		"docker.io/library/ubuntu":     "registry-1.docker.io",
+		"quay.io/foo/bar:latest":       "quay.io",
+		"localhost/foo":                "localhost",
+		"registry:5000/foo@sha256:abc": "registry:5000",
+	}
+
+	for image, expected := range cases {
+		if host := registryHost(image); host != expected {
+			t.Fatalf("bad host for %s: %s", image, host)
+		}
+	}
+}
+
+func TestUsedResourceLimitFlags(t *testing.T) {
+	args := []string{"-d", "--cpus", "2", "-m=1g", "--", "{{.Image}}", "--memory"}
+	used := usedResourceLimitFlags(args)
+	if strings.Join(used, " ") != "--cpus -m" {
+		t.Fatalf("bad: %#v", used)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[uint64]string{
+		512:             "512 B",
+		2048:            "2.0 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+
+	for b, expected := range cases {
+		if s := formatBytes(b); s != expected {
+			t.Fatalf("bad format for %d: %s", b, s)
+		}
+	}
+}
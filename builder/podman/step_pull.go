@@ -2,14 +2,22 @@ package podman
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 )
 
-type StepPull struct{}
+type StepPull struct {
+	GeneratedData *packerbuilderdata.GeneratedData
+}
 
 func (s *StepPull) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	ui := state.Get("ui").(packersdk.Ui)
@@ -21,14 +29,82 @@ func (s *StepPull) Run(ctx context.Context, state multistep.StateBag) multistep.
 		return multistep.ActionHalt
 	}
 
-	if !config.Pull {
-		log.Println("Pull disabled, won't podman pull")
-		return multistep.ActionContinue
+	driver := state.Get("driver").(Driver)
+
+	image, err := s.pull(ctx, state, driver, config)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
 	}
 
-	ui.Say(fmt.Sprintf("Pulling Podman image: %s", config.Image))
+	// Record which base image the build actually starts from, inspecting it
+	// unless pull already did and left it as it was.
+	if image == nil {
+		if image, err = driver.InspectImage(config.Image); err != nil {
+			log.Printf("[WARN] Error inspecting base image: %s", err)
+		}
+	}
+	if s.GeneratedData != nil {
+		digest := "ERR_SOURCE_IMAGE_DIGEST_NOT_FOUND"
+		if image != nil {
+			digest = image.Digest
+		}
+		s.GeneratedData.Put("SourceImageDigest", digest)
+	}
 
-	driver := state.Get("driver").(Driver)
+	if config.VerifyBaseDigest {
+		if image == nil {
+			err = fmt.Errorf("Error inspecting base image digests: %s", err)
+		} else {
+			err = verifyBaseDigest(image, config.ImageDigest)
+		}
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Message(fmt.Sprintf("Base image digest verified: %s", config.ImageDigest))
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepPull) Cleanup(state multistep.StateBag) {
+}
+
+// pull fetches the image according to the configured pull policy. When the
+// newer policy keeps the local image, it returns what it inspected of it.
+func (s *StepPull) pull(ctx context.Context, state multistep.StateBag, driver Driver, config *Config) (*ImageInspect, error) {
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if config.PullPolicy == "never" {
+		log.Println("Pull policy is never, won't podman pull")
+		return nil, nil
+	}
+
+	// Both the missing and newer policies need to know whether the image is
+	// already around.
+	exists := false
+	if config.PullPolicy == "missing" || config.PullPolicy == "newer" {
+		var err error
+		exists, err = driver.ImageExists(config.Image)
+		if err != nil {
+			return nil, fmt.Errorf("Error looking up Podman image: %s", err)
+		}
+
+		if exists && config.PullPolicy == "missing" {
+			ui.Say(fmt.Sprintf("Podman image %s found locally, won't pull", config.Image))
+			return nil, nil
+		}
+	}
+
+	checkNewer := exists && config.PullPolicy == "newer"
+	if checkNewer {
+		ui.Say(fmt.Sprintf("Checking the registry for a newer Podman image: %s", config.Image))
+	} else {
+		ui.Say(fmt.Sprintf("Pulling Podman image: %s", config.Image))
+	}
 
 	if config.Login {
 		ui.Message("Logging in...")
@@ -37,10 +113,7 @@ func (s *StepPull) Run(ctx context.Context, state multistep.StateBag) multistep.
 			config.LoginUsername,
 			config.LoginPassword)
 		if err != nil {
-			err := fmt.Errorf("Error logging in: %s", err)
-			state.Put("error", err)
-			ui.Error(err.Error())
-			return multistep.ActionHalt
+			return nil, fmt.Errorf("Error logging in: %s", err)
 		}
 
 		defer func() {
@@ -52,15 +125,171 @@ func (s *StepPull) Run(ctx context.Context, state multistep.StateBag) multistep.
 		}()
 	}
 
-	if err := driver.Pull(config.Image); err != nil {
-		err := fmt.Errorf("Error pulling Podman image: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+	authFile := config.AuthFile
+	if len(config.RegistryAuth) > 0 {
+		// The temp dir is shared with the container later on, so make sure
+		// the credentials are gone by then.
+		authFile = filepath.Join(state.Get("temp_dir").(string), "auth.json")
+		if err := writeAuthFile(authFile, config.RegistryAuth); err != nil {
+			return nil, fmt.Errorf("Error writing auth file: %s", err)
+		}
+		defer os.Remove(authFile)
 	}
+	options := pullOptions(config, authFile)
 
-	return multistep.ActionContinue
+	if checkNewer {
+		local, err := driver.InspectImage(config.Image)
+		if err != nil {
+			return nil, fmt.Errorf("Error inspecting Podman image: %s", err)
+		}
+
+		upToDate, err := localImageUpToDate(driver, config.Image, local, options)
+		switch {
+		case err != nil && isNetworkError(err):
+			// A local copy is good enough when the registry can't be
+			// reached, so that offline rebuilds still work.
+			ui.Message(fmt.Sprintf("Registry can't be reached, using the local image: %s", err))
+			return local, nil
+		case err != nil:
+			return nil, fmt.Errorf("Error checking the registry for a newer image: %s", err)
+		case upToDate:
+			ui.Message("Local image is up to date, won't pull")
+			return local, nil
+		}
+
+		ui.Message("The registry has a newer image, pulling it")
+	}
+
+	if err := pullWithRetries(ctx, ui, driver, config, options); err != nil {
+		return nil, fmt.Errorf("Error pulling Podman image: %s", err)
+	}
+
+	return nil, nil
 }
 
-func (s *StepPull) Cleanup(state multistep.StateBag) {
+// pullOptions returns the options to pull the configured image with.
+func pullOptions(config *Config, authFile string) PullOptions {
+	return PullOptions{
+		Platform:      config.PullPlatform,
+		TLSVerify:     config.TLSVerify.ToBoolPointer(),
+		CertDir:       config.CertDir,
+		AuthFile:      authFile,
+		DecryptionKey: config.DecryptionKey,
+	}
+}
+
+// localImageUpToDate reports whether local, the local copy of image, is the
+// one the registry currently serves for it.
+func localImageUpToDate(driver Driver, image string, local *ImageInspect, options PullOptions) (bool, error) {
+	manifest, err := driver.RemoteManifest(image, options)
+	if err != nil {
+		return false, err
+	}
+
+	return manifestMatches(manifest, local)
+}
+
+// manifestMatches reports whether a manifest, as printed by `podman manifest
+// inspect`, describes the local image. podman reformats the manifest, so
+// its own digest can't be computed. Instead, a manifest list matches when it
+// lists one of the digests of the local image, and an image manifest
+// matches when its config is the one of the local image, whose ID is the
+// digest of its config.
+func manifestMatches(manifest []byte, local *ImageInspect) (bool, error) {
+	var parsed struct {
+		Manifests []struct {
+			Digest string
+		}
+		Config struct {
+			Digest string
+		}
+	}
+	if err := json.Unmarshal(manifest, &parsed); err != nil {
+		return false, fmt.Errorf("Error parsing manifest: %s", err)
+	}
+
+	if len(parsed.Manifests) > 0 {
+		for _, m := range parsed.Manifests {
+			for _, repoDigest := range local.RepoDigests {
+				if strings.HasSuffix(repoDigest, "@"+m.Digest) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+
+	if parsed.Config.Digest == "" {
+		return false, fmt.Errorf("Error parsing manifest: no config nor manifests")
+	}
+	return strings.TrimPrefix(parsed.Config.Digest, "sha256:") ==
+		strings.TrimPrefix(local.Id, "sha256:"), nil
+}
+
+// networkErrors are the messages of the errors that mean the registry
+// couldn't be reached, rather than that it refused the request.
+var networkErrors = []string{
+	"connection refused",
+	"connection reset",
+	"connection timed out",
+	"i/o timeout",
+	"network is unreachable",
+	"no route to host",
+	"no such host",
+	"server misbehaving",
+	"temporary failure in name resolution",
+	"tls handshake timeout",
+}
+
+// isNetworkError reports whether err, as returned by the driver along with
+// the stderr of podman, is due to the registry being unreachable.
+func isNetworkError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, networkErr := range networkErrors {
+		if strings.Contains(msg, networkErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// pullWithRetries pulls the image, retrying up to config.PullRetries times
+// on failure.
+func pullWithRetries(ctx context.Context, ui packersdk.Ui, driver Driver, config *Config, options PullOptions) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = driver.Pull(config.Image, options); err == nil || attempt >= config.PullRetries {
+			return err
+		}
+
+		ui.Message(fmt.Sprintf("Pull failed, retrying in %s (%d/%d): %s",
+			config.PullRetryDelay, attempt+1, config.PullRetries, err))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(config.PullRetryDelay):
+		}
+	}
+}
+
+// verifyBaseDigest makes sure the image in local storage is the one pinned
+// in the configuration, by one of the digests it is known by in its
+// repositories, such as the digest of its manifest list.
+func verifyBaseDigest(image *ImageInspect, expected string) error {
+	// Entries look like repository@sha256:..., keep only the digest
+	digests := make([]string, 0, len(image.RepoDigests))
+	for _, repoDigest := range image.RepoDigests {
+		if i := strings.LastIndex(repoDigest, "@"); i != -1 {
+			digests = append(digests, repoDigest[i+1:])
+		}
+	}
+
+	for _, digest := range digests {
+		if digest == expected {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Base image digest mismatch: expected %s, got %v",
+		expected, digests)
 }
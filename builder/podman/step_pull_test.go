@@ -3,9 +3,15 @@ package podman
 import (
 	"context"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+	packerconfig "github.com/hashicorp/packer-plugin-sdk/template/config"
 )
 
 func TestStepPull_impl(t *testing.T) {
@@ -88,7 +94,7 @@ func TestStepPull_noPull(t *testing.T) {
 	defer step.Cleanup(state)
 
 	config := state.Get("config").(*Config)
-	config.Pull = false
+	config.PullPolicy = "never"
 
 	driver := state.Get("driver").(*MockDriver)
 
@@ -102,3 +108,299 @@ func TestStepPull_noPull(t *testing.T) {
 		t.Fatal("shouldn't have pulled")
 	}
 }
+
+func TestStepPull_missing(t *testing.T) {
+	state := testState(t)
+	step := new(StepPull)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.PullPolicy = "missing"
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageExistsResult = true
+
+	// run the step with the image present
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.ImageExistsImage != config.Image {
+		t.Fatalf("bad: %#v", driver.ImageExistsImage)
+	}
+	if driver.PullCalled {
+		t.Fatal("shouldn't have pulled")
+	}
+
+	// run the step with the image absent
+	driver.ImageExistsResult = false
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if !driver.PullCalled {
+		t.Fatal("should've pulled")
+	}
+}
+
+func TestStepPull_newer(t *testing.T) {
+	state := testState(t)
+	step := new(StepPull)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.PullPolicy = "newer"
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageExistsResult = true
+	driver.InspectImageResult = &ImageInspect{
+		Id:          "abc",
+		RepoDigests: []string{"quay.io/foo/bar@sha256:list", "quay.io/foo/bar@sha256:amd64"},
+	}
+
+	// run the step with the local image still in the manifest list
+	driver.RemoteManifestResult = []byte(`{"manifests": [{"digest": "sha256:amd64"}, {"digest": "sha256:arm64"}]}`)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.RemoteManifestImage != config.Image {
+		t.Fatalf("bad: %#v", driver.RemoteManifestImage)
+	}
+	if driver.PullCalled {
+		t.Fatal("shouldn't have pulled")
+	}
+
+	// run the step with a new image in the registry
+	driver.RemoteManifestResult = []byte(`{"manifests": [{"digest": "sha256:new"}]}`)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if !driver.PullCalled {
+		t.Fatal("should've pulled")
+	}
+}
+
+func TestStepPull_newerOffline(t *testing.T) {
+	state := testState(t)
+	step := new(StepPull)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.PullPolicy = "newer"
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ImageExistsResult = true
+	driver.RemoteManifestErr = errors.New("Error: exit status 125\n\nStderr: dial tcp: lookup quay.io: no such host")
+
+	// run the step, the local image should be used
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.PullCalled {
+		t.Fatal("shouldn't have pulled")
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("shouldn't have error")
+	}
+
+	// errors from a registry that could be reached are fatal
+	driver.RemoteManifestErr = errors.New("Error: exit status 125\n\nStderr: unauthorized: authentication required")
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.PullCalled {
+		t.Fatal("shouldn't have pulled")
+	}
+
+	// without a local image the registry isn't checked and the pull error
+	// is fatal
+	driver.ImageExistsResult = false
+	driver.RemoteManifestCalled = false
+	driver.PullError = errors.New("foo")
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.RemoteManifestCalled {
+		t.Fatal("shouldn't have checked the registry")
+	}
+}
+
+func TestManifestMatches(t *testing.T) {
+	local := &ImageInspect{
+		Id:          "abc",
+		RepoDigests: []string{"quay.io/foo/bar@sha256:amd64"},
+	}
+
+	cases := []struct {
+		manifest string
+		matches  bool
+	}{
+		{`{"manifests": [{"digest": "sha256:arm64"}, {"digest": "sha256:amd64"}]}`, true},
+		{`{"manifests": [{"digest": "sha256:arm64"}]}`, false},
+		{`{"config": {"digest": "sha256:abc"}}`, true},
+		{`{"config": {"digest": "sha256:def"}}`, false},
+	}
+	for _, tc := range cases {
+		matches, err := manifestMatches([]byte(tc.manifest), local)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.manifest, err)
+		}
+		if matches != tc.matches {
+			t.Fatalf("%s: expected %t, got %t", tc.manifest, tc.matches, matches)
+		}
+	}
+
+	if _, err := manifestMatches([]byte(`{}`), local); err == nil {
+		t.Fatal("should error on a manifest without config nor manifests")
+	}
+}
+
+func TestStepPull_generatedData(t *testing.T) {
+	state := testState(t)
+	step := new(StepPull)
+	step.GeneratedData = &packerbuilderdata.GeneratedData{State: state}
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.InspectImageResult = &ImageInspect{Digest: "sha256:foo"}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	genData := state.Get("generated_data").(map[string]interface{})
+	if digest := genData["SourceImageDigest"].(string); digest != "sha256:foo" {
+		t.Fatalf("bad: %s", digest)
+	}
+}
+
+func TestStepPull_verifyBaseDigest(t *testing.T) {
+	state := testState(t)
+	step := new(StepPull)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ImageDigest = "sha256:foo"
+	config.VerifyBaseDigest = true
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.InspectImageResult = &ImageInspect{
+		RepoDigests: []string{"quay.io/foo/bar@sha256:bar", "quay.io/foo/bar@sha256:foo"},
+	}
+
+	// run the step with a matching digest
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.InspectImageImage != config.Image {
+		t.Fatalf("bad: %#v", driver.InspectImageImage)
+	}
+
+	// run the step with a mismatching digest
+	driver.InspectImageResult.RepoDigests = []string{"quay.io/foo/bar@sha256:bar"}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepPull_options(t *testing.T) {
+	state := testState(t)
+	step := new(StepPull)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.PullPlatform = "linux/arm64"
+	config.TLSVerify = packerconfig.TriFalse
+	config.AuthFile = "auth.json"
+
+	driver := state.Get("driver").(*MockDriver)
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	options := driver.PullOptions
+	if options.Platform != "linux/arm64" {
+		t.Fatalf("bad: %#v", options.Platform)
+	}
+	if options.TLSVerify == nil || *options.TLSVerify {
+		t.Fatalf("bad: %#v", options.TLSVerify)
+	}
+	if options.AuthFile != "auth.json" {
+		t.Fatalf("bad: %#v", options.AuthFile)
+	}
+	if options.CertDir != "" {
+		t.Fatalf("bad: %#v", options.CertDir)
+	}
+}
+
+func TestStepPull_retries(t *testing.T) {
+	state := testState(t)
+	step := new(StepPull)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.PullRetries = 2
+	config.PullRetryDelay = time.Millisecond
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.PullError = errors.New("foo")
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.PullCount != 3 {
+		t.Fatalf("bad: %d", driver.PullCount)
+	}
+}
+
+func TestStepPull_registryAuth(t *testing.T) {
+	state := testState(t)
+	step := new(StepPull)
+	defer step.Cleanup(state)
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	state.Put("temp_dir", td)
+
+	config := state.Get("config").(*Config)
+	config.RegistryAuth = []RegistryAuth{
+		{Server: "quay.io", Username: "foo", Password: "bar"},
+	}
+
+	driver := state.Get("driver").(*MockDriver)
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we pulled with a temporary auth file and didn't login
+	if driver.LoginCalled {
+		t.Fatal("should not have logged in")
+	}
+	authFile := driver.PullOptions.AuthFile
+	if filepath.Dir(authFile) != td {
+		t.Fatalf("bad: %#v", authFile)
+	}
+
+	// verify the credentials didn't outlive the pull
+	if _, err := os.Stat(authFile); !os.IsNotExist(err) {
+		t.Fatalf("auth file should be removed: %s", err)
+	}
+}
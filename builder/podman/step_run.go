@@ -3,9 +3,11 @@ package podman
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
 )
 
 type StepRun struct {
@@ -22,9 +24,19 @@ func (s *StepRun) Run(ctx context.Context, state multistep.StateBag) multistep.S
 		return multistep.ActionHalt
 	}
 
+	tempDir := state.Get("temp_dir").(string)
+	runCommand, err := config.renderRunCommand(config.runCommandData(
+		tempDir, fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())))
+	if err != nil {
+		err := fmt.Errorf("Error rendering run_command: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	runConfig := ContainerConfig{
 		Image:      config.Image,
-		RunCommand: config.RunCommand,
+		RunCommand: runCommand,
 		Device:     config.Device,
 		TmpFs:      config.TmpFs,
 		Volumes:    make(map[string]string),
@@ -32,16 +44,52 @@ func (s *StepRun) Run(ctx context.Context, state multistep.StateBag) multistep.S
 		CapDrop:    config.CapDrop,
 		Privileged: config.Privileged,
 		Systemd:    config.Systemd,
+		StopSignal: config.StopSignal,
+		Userns:     config.Userns,
+		Mounts:     config.Mounts,
+	}
+
+	if podId, ok := state.GetOk("pod_id"); ok {
+		runConfig.Pod = podId.(string)
+	}
+	if secrets, ok := state.GetOk("secrets"); ok {
+		runConfig.Secrets = secrets.(map[string]string)
 	}
 
 	for host, container := range config.Volumes {
 		runConfig.Volumes[host] = container
 	}
+	for name, container := range config.CacheMounts {
+		runConfig.Volumes[name] = container
+	}
 
-	tempDir := state.Get("temp_dir").(string)
-	runConfig.Volumes[tempDir] = config.ContainerDir
+	// The temporary directory is only shared with this container, so it is
+	// given a private SELinux label for the container to be allowed to use
+	// it. podman ignores the option when SELinux is disabled.
+	runConfig.Volumes[tempDir] = config.ContainerDir + ":Z"
+
+	if config.ForwardSSHAgent {
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			state.Put("error", errNoSSHAgent)
+			ui.Error(errNoSSHAgent.Error())
+			return multistep.ActionHalt
+		}
+		runConfig.Volumes[socket] = sshAgentSocket(config)
+	}
+
+	// The proxy variables podman sets by default would be committed along
+	// with the container configuration, they are set for the exec sessions
+	// instead.
+	runConfig.DisableHTTPProxy = config.ForwardProxyEnv
 
 	driver := state.Get("driver").(Driver)
+	if err := checkPodmanFeatures(driver, config); err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	ui.Say("Starting podman container...")
 	containerId, err := driver.StartContainer(&runConfig)
 	if err != nil {
@@ -68,14 +116,33 @@ func (s *StepRun) Cleanup(state multistep.StateBag) {
 
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packersdk.Ui)
+	config := state.Get("config").(*Config)
+
+	// Leave the container around for debugging if the build failed. The ID
+	// stays in the state so the builder can tell the user how to reach it.
+	_, halted := state.GetOk(multistep.StateHalted)
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	if halted && !cancelled && config.KeepContainerOnError {
+		s.containerId = ""
+		return
+	}
 
-	// Kill the container. We don't handle errors because errors usually
-	// just mean that the container doesn't exist anymore, which isn't a
-	// big deal.
-	ui.Say(fmt.Sprintf("Killing the container: %s", s.containerId))
+	// Give the container a chance to shut down cleanly, then make sure it is
+	// gone. Errors are reported but don't fail the build, since they usually
+	// just mean that the container doesn't exist anymore.
+	ui.Say(fmt.Sprintf("Stopping the container: %s", s.containerId))
+	if err := driver.StopContainer(s.containerId, config.StopTimeout); err != nil {
+		ui.Error(fmt.Sprintf("Error stopping the container, killing it: %s", err))
+		if err := driver.KillContainer(s.containerId); err != nil {
+			ui.Error(fmt.Sprintf("Error killing the container: %s", err))
+		}
+	}
 
-	//nolint:errcheck
-	driver.KillContainer(s.containerId)
+	ui.Say(fmt.Sprintf("Removing the container: %s", s.containerId))
+	if err := driver.RemoveContainer(s.containerId); err != nil {
+		ui.Error(fmt.Sprintf("Error removing the container: %s", err))
+	}
+	state.Remove("container_id")
 
 	// Reset the container ID so that we're idempotent
 	s.containerId = ""
@@ -3,8 +3,11 @@ package podman
 import (
 	"context"
 	"errors"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 )
 
@@ -39,6 +42,12 @@ func TestStepRun(t *testing.T) {
 	if driver.StartConfig.Image != config.Image {
 		t.Fatalf("bad: %#v", driver.StartConfig.Image)
 	}
+	if driver.StartConfig.Userns != config.Userns {
+		t.Fatalf("bad: %#v", driver.StartConfig.Userns)
+	}
+	if driver.StartConfig.Volumes["/foo"] != "/packer-files:Z" {
+		t.Fatalf("bad: %#v", driver.StartConfig.Volumes)
+	}
 
 	// verify the ID is saved
 	idRaw, ok := state.GetOk("container_id")
@@ -52,18 +61,217 @@ func TestStepRun(t *testing.T) {
 	}
 
 	// Verify we haven't called stop yet
-	if driver.KillCalled {
+	if driver.StopCalled {
 		t.Fatal("should not have stopped")
 	}
 
 	// Cleanup
 	step.Cleanup(state)
-	if !driver.KillCalled {
+	if !driver.StopCalled {
 		t.Fatal("should've stopped")
 	}
-	if driver.KillID != id {
+	if driver.StopID != id {
 		t.Fatalf("bad: %#v", driver.StopID)
 	}
+	if driver.StopTimeout != config.StopTimeout {
+		t.Fatalf("bad: %#v", driver.StopTimeout)
+	}
+	if driver.KillCalled {
+		t.Fatal("should not have killed")
+	}
+	if !driver.RemoveCalled {
+		t.Fatal("should've removed")
+	}
+	if driver.RemoveID != id {
+		t.Fatalf("bad: %#v", driver.RemoveID)
+	}
+}
+
+func TestStepRun_pod(t *testing.T) {
+	state := testStepRunState(t)
+	state.Put("pod_id", "pod")
+	step := new(StepRun)
+	defer step.Cleanup(state)
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the container joined the pod
+	driver := state.Get("driver").(*MockDriver)
+	if driver.StartConfig.Pod != "pod" {
+		t.Fatalf("bad: %#v", driver.StartConfig.Pod)
+	}
+}
+
+func TestStepRun_secrets(t *testing.T) {
+	state := testStepRunState(t)
+	state.Put("secrets", map[string]string{"packer-token-1234": "token"})
+	step := new(StepRun)
+	defer step.Cleanup(state)
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the secrets are mounted
+	driver := state.Get("driver").(*MockDriver)
+	if driver.StartConfig.Secrets["packer-token-1234"] != "token" {
+		t.Fatalf("bad: %#v", driver.StartConfig.Secrets)
+	}
+}
+
+func TestStepRun_cacheMounts(t *testing.T) {
+	state := testStepRunState(t)
+	step := new(StepRun)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.CacheMounts = map[string]string{"dnf-cache": "/var/cache/dnf"}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the cache volume is mounted
+	driver := state.Get("driver").(*MockDriver)
+	if driver.StartConfig.Volumes["dnf-cache"] != "/var/cache/dnf" {
+		t.Fatalf("bad: %#v", driver.StartConfig.Volumes)
+	}
+}
+
+func TestStepRun_forward(t *testing.T) {
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Setenv("SSH_AUTH_SOCK", "/tmp/ssh-agent/agent.1234")
+
+	state := testStepRunState(t)
+	step := new(StepRun)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ForwardSSHAgent = true
+	config.ForwardProxyEnv = true
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the agent socket is mounted, and the proxy variables left out
+	driver := state.Get("driver").(*MockDriver)
+	if driver.StartConfig.Volumes["/tmp/ssh-agent/agent.1234"] != "/packer-files/ssh-agent.sock" {
+		t.Fatalf("bad: %#v", driver.StartConfig.Volumes)
+	}
+	if !driver.StartConfig.DisableHTTPProxy {
+		t.Fatal("should've disabled the proxy variables")
+	}
+}
+
+func TestStepRun_forwardNoAgent(t *testing.T) {
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Setenv("SSH_AUTH_SOCK", "")
+
+	state := testStepRunState(t)
+	step := new(StepRun)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ForwardSSHAgent = true
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify nothing was started
+	driver := state.Get("driver").(*MockDriver)
+	if driver.StartCalled {
+		t.Fatal("should not have started")
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepRun_runCommand(t *testing.T) {
+	state := testStepRunState(t)
+	step := new(StepRun)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.RunCommand = []string{"-d", "--name", "{{.ContainerName}}", "-v", "{{.TempDir}}:/src", "--", "{{.Image}}"}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the run command was rendered with the build values
+	driver := state.Get("driver").(*MockDriver)
+	args := driver.StartConfig.RunCommand
+	if len(args) != 7 || !strings.HasPrefix(args[2], "packer-") || args[4] != "/foo:/src" || args[6] != config.Image {
+		t.Fatalf("bad: %#v", args)
+	}
+}
+
+func TestStepRun_unsupportedPodman(t *testing.T) {
+	state := testStepRunState(t)
+	step := new(StepRun)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Systemd = "always"
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.PodmanVersionResult = &PodmanVersion{
+		Client: version.Must(version.NewVersion("1.9.3")),
+	}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we failed before starting anything
+	if !driver.PodmanVersionCalled {
+		t.Fatal("should've probed podman")
+	}
+	if driver.StartCalled {
+		t.Fatal("should not have started")
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepRun_stopError(t *testing.T) {
+	state := testStepRunState(t)
+	step := new(StepRun)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+	driver.StopError = errors.New("foo")
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// Cleanup falls back to killing the container
+	step.Cleanup(state)
+	if !driver.KillCalled {
+		t.Fatal("should've killed")
+	}
+	if driver.KillID != "foo" {
+		t.Fatalf("bad: %#v", driver.KillID)
+	}
+	if !driver.RemoveCalled {
+		t.Fatal("should've removed")
+	}
 }
 
 func TestStepRun_error(t *testing.T) {
@@ -85,13 +293,66 @@ func TestStepRun_error(t *testing.T) {
 	}
 
 	// Verify we haven't called stop yet
-	if driver.KillCalled {
+	if driver.StopCalled {
 		t.Fatal("should not have stopped")
 	}
 
 	// Cleanup
 	step.Cleanup(state)
-	if driver.KillCalled {
+	if driver.StopCalled || driver.RemoveCalled {
+		t.Fatal("should not have stopped")
+	}
+}
+
+func TestStepRun_keepContainerOnError(t *testing.T) {
+	state := testStepRunState(t)
+	step := new(StepRun)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.KeepContainerOnError = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// Cleanup after a failed build
+	state.Put(multistep.StateHalted, true)
+	step.Cleanup(state)
+	if driver.StopCalled || driver.RemoveCalled {
 		t.Fatal("should not have stopped")
 	}
+
+	// verify the ID is still available
+	if _, ok := state.GetOk("container_id"); !ok {
+		t.Fatal("should've kept container ID")
+	}
+}
+
+func TestStepRun_keepContainerOnErrorSuccess(t *testing.T) {
+	state := testStepRunState(t)
+	step := new(StepRun)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.KeepContainerOnError = true
+	driver := state.Get("driver").(*MockDriver)
+	driver.StartID = "foo"
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// Cleanup after a successful build
+	step.Cleanup(state)
+	if !driver.StopCalled || !driver.RemoveCalled {
+		t.Fatal("should've stopped")
+	}
+	if _, ok := state.GetOk("container_id"); ok {
+		t.Fatal("should've removed container ID")
+	}
 }
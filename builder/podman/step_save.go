@@ -0,0 +1,59 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepSave saves the committed image to an archive.
+type StepSave struct{}
+
+func (s *StepSave) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	config, ok := state.Get("config").(*Config)
+	if !ok {
+		err := fmt.Errorf("error encountered obtaining podman config")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	// Make the directory we're saving to if it doesn't exist
+	saveDir := filepath.Dir(config.SavePath)
+	if err := os.MkdirAll(saveDir, 0755); err != nil {
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// Open the file that we're going to write to
+	f, err := os.Create(config.SavePath)
+	if err != nil {
+		err := fmt.Errorf("Error creating output file: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	driver := state.Get("driver").(Driver)
+	imageId := state.Get("image_id").(string)
+
+	ui.Say("Saving the image")
+	if err := driver.SaveImage(imageId, "", f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	f.Close()
+	return multistep.ActionContinue
+}
+
+func (s *StepSave) Cleanup(state multistep.StateBag) {}
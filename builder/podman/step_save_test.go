@@ -0,0 +1,101 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func testStepSaveState(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	state.Put("image_id", "bar")
+	return state
+}
+
+func TestStepSave_impl(t *testing.T) {
+	var _ multistep.Step = new(StepSave)
+}
+
+func TestStepSave(t *testing.T) {
+	state := testStepSaveState(t)
+	step := new(StepSave)
+	defer step.Cleanup(state)
+
+	// Create a tempfile for our output path
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	config := state.Get("config").(*Config)
+	config.SavePath = tf.Name()
+	driver := state.Get("driver").(*MockDriver)
+	driver.SaveImageReader = bytes.NewReader([]byte("image!"))
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we did the right thing
+	if !driver.SaveImageCalled {
+		t.Fatal("should've saved")
+	}
+	if driver.SaveImageId != "bar" {
+		t.Fatalf("bad: %#v", driver.SaveImageId)
+	}
+
+	// verify the data saved to the file
+	contents, err := ioutil.ReadFile(tf.Name())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if string(contents) != "image!" {
+		t.Fatalf("bad: %#v", string(contents))
+	}
+}
+
+func TestStepSave_error(t *testing.T) {
+	state := testStepSaveState(t)
+	step := new(StepSave)
+	defer step.Cleanup(state)
+
+	// Create a tempfile for our output path
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+
+	if err := os.Remove(tf.Name()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config := state.Get("config").(*Config)
+	config.SavePath = tf.Name()
+	driver := state.Get("driver").(*MockDriver)
+	driver.SaveImageError = errors.New("foo")
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we have an error
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+
+	// verify we didn't make that file
+	if _, err := os.Stat(tf.Name()); err == nil {
+		t.Fatal("save path shouldn't exist")
+	}
+}
@@ -0,0 +1,74 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+)
+
+// StepSecrets creates the podman secrets mounted into the build container,
+// and removes them once the build is over.
+type StepSecrets struct {
+	secretIds []string
+}
+
+func (s *StepSecrets) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	config, ok := state.Get("config").(*Config)
+	if !ok {
+		err := fmt.Errorf("error encountered obtaining podman config")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if len(config.Secrets) == 0 {
+		return multistep.ActionContinue
+	}
+
+	driver := state.Get("driver").(Driver)
+
+	// Secret names are global to the podman host, so make them unique to
+	// this build and mount them under the name from the template.
+	secrets := make(map[string]string)
+	for _, secret := range config.Secrets {
+		ui.Say(fmt.Sprintf("Creating secret %s", secret.Name))
+		id, err := driver.CreateSecret(
+			fmt.Sprintf("packer-%s-%s", secret.Name, uuid.TimeOrderedUUID()), secret.value)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		s.secretIds = append(s.secretIds, id)
+		secrets[id] = secret.Name
+	}
+	state.Put("secrets", secrets)
+
+	return multistep.ActionContinue
+}
+
+func (s *StepSecrets) Cleanup(state multistep.StateBag) {
+	if len(s.secretIds) == 0 {
+		return
+	}
+
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	// Secrets are removed even when the container is kept for debugging:
+	// it holds its own copy of them.
+	ui.Say("Removing the secrets...")
+	for _, id := range s.secretIds {
+		if err := driver.RemoveSecret(id); err != nil {
+			ui.Error(fmt.Sprintf("Error removing the secret, remove it with: podman secret rm %s: %s", id, err))
+		}
+	}
+	state.Remove("secrets")
+
+	// Reset the secret IDs so that we're idempotent
+	s.secretIds = nil
+}
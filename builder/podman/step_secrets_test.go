@@ -0,0 +1,107 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func testStepSecretsState(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.Secrets = []SecretConfig{
+		{Name: "token", value: []byte("s3cr3t-token")},
+		{Name: "netrc", value: []byte("machine mirror password hunter2")},
+	}
+	return state
+}
+
+func TestStepSecrets_impl(t *testing.T) {
+	var _ multistep.Step = new(StepSecrets)
+}
+
+func TestStepSecrets(t *testing.T) {
+	state := testStepSecretsState(t)
+	step := new(StepSecrets)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the secrets were created under unique names
+	if driver.CreateSecretCount != 2 {
+		t.Fatalf("bad: %d", driver.CreateSecretCount)
+	}
+	if !strings.HasPrefix(driver.CreateSecretNames[0], "packer-token-") {
+		t.Fatalf("bad: %#v", driver.CreateSecretNames)
+	}
+	if driver.CreateSecretValues[1] != "machine mirror password hunter2" {
+		t.Fatalf("bad: %#v", driver.CreateSecretValues)
+	}
+
+	// verify they are mounted under their own name
+	secrets := state.Get("secrets").(map[string]string)
+	if secrets[driver.CreateSecretNames[0]] != "token" || secrets[driver.CreateSecretNames[1]] != "netrc" {
+		t.Fatalf("bad: %#v", secrets)
+	}
+
+	// Cleanup
+	step.Cleanup(state)
+	if strings.Join(driver.RemoveSecretIDs, " ") != strings.Join(driver.CreateSecretNames, " ") {
+		t.Fatalf("bad: %#v", driver.RemoveSecretIDs)
+	}
+	if _, ok := state.GetOk("secrets"); ok {
+		t.Fatal("should've forgotten the secrets")
+	}
+}
+
+func TestStepSecrets_noSecrets(t *testing.T) {
+	state := testState(t)
+	step := new(StepSecrets)
+	defer step.Cleanup(state)
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	driver := state.Get("driver").(*MockDriver)
+	if driver.CreateSecretCount != 0 {
+		t.Fatal("should not have created secrets")
+	}
+	if _, ok := state.GetOk("secrets"); ok {
+		t.Fatal("should not have secrets")
+	}
+}
+
+func TestStepSecrets_error(t *testing.T) {
+	state := testStepSecretsState(t)
+	step := new(StepSecrets)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.CreateSecretErr = errors.New("foo")
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we have an error
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+
+	// verify nothing is left to remove
+	step.Cleanup(state)
+	if len(driver.RemoveSecretIDs) != 0 {
+		t.Fatalf("bad: %#v", driver.RemoveSecretIDs)
+	}
+}
@@ -0,0 +1,122 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepServices creates the pod the build container shares with its service
+// containers, starts the services and waits for them to be healthy.
+type StepServices struct {
+	podId string
+}
+
+func (s *StepServices) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	config, ok := state.Get("config").(*Config)
+	if !ok {
+		err := fmt.Errorf("error encountered obtaining podman config")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if len(config.Services) == 0 {
+		return multistep.ActionContinue
+	}
+
+	driver := state.Get("driver").(Driver)
+
+	podConfig := PodConfig{Userns: config.Userns}
+	for _, service := range config.Services {
+		podConfig.Hosts = append(podConfig.Hosts, service.Name)
+		podConfig.Ports = append(podConfig.Ports, service.Ports...)
+	}
+
+	ui.Say("Creating a pod for the services...")
+	podId, err := driver.CreatePod(&podConfig)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.podId = podId
+	state.Put("pod_id", podId)
+	ui.Message(fmt.Sprintf("Pod ID: %s", podId))
+
+	for i := range config.Services {
+		service := &config.Services[i]
+
+		ui.Say(fmt.Sprintf("Starting service %s from %s", service.Name, service.Image))
+		id, err := driver.StartService(podId, service)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		if service.HealthCommand == "" {
+			continue
+		}
+
+		ui.Message(fmt.Sprintf("Waiting for service %s to be healthy...", service.Name))
+		if err := waitHealthy(ctx, driver, id, service); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepServices) Cleanup(state multistep.StateBag) {
+	if s.podId == "" {
+		return
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+
+	// Removing the pod would take a kept build container with it
+	if _, ok := state.GetOk("container_id"); ok {
+		ui.Message(fmt.Sprintf("Pod %s kept along with the container, remove it with: podman pod rm -f %s",
+			s.podId, s.podId))
+		s.podId = ""
+		return
+	}
+
+	driver := state.Get("driver").(Driver)
+	ui.Say(fmt.Sprintf("Removing the pod and its services: %s", s.podId))
+	if err := driver.RemovePod(s.podId); err != nil {
+		ui.Error(fmt.Sprintf("Error removing the pod: %s", err))
+	}
+	state.Remove("pod_id")
+
+	// Reset the pod ID so that we're idempotent
+	s.podId = ""
+}
+
+// waitHealthy runs the health check of the service container until it
+// passes or the health timeout of the service expires.
+func waitHealthy(ctx context.Context, driver Driver, id string, service *ServiceConfig) error {
+	timeout := time.After(service.HealthTimeout)
+	for {
+		err := driver.HealthCheck(id)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("Service %s isn't healthy after %s: %s",
+				service.Name, service.HealthTimeout, err)
+		case <-time.After(service.HealthInterval):
+		}
+	}
+}
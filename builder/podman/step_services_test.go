@@ -0,0 +1,149 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func testStepServicesState(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.Services = []ServiceConfig{
+		{Name: "db", Image: "postgres", Ports: []string{"5432:5432"}},
+		{
+			Name:           "api",
+			Image:          "mockserver",
+			HealthCommand:  "curl -f localhost:1080",
+			HealthInterval: time.Millisecond,
+			HealthTimeout:  time.Second,
+		},
+	}
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.CreatePodID = "pod"
+	driver.StartServiceID = "service"
+	return state
+}
+
+func TestStepServices_impl(t *testing.T) {
+	var _ multistep.Step = new(StepServices)
+}
+
+func TestStepServices(t *testing.T) {
+	state := testStepServicesState(t)
+	step := new(StepServices)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.HealthCheckErrs = []error{errors.New("starting")}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the pod is set up for the services
+	if strings.Join(driver.CreatePodConfig.Hosts, " ") != "db api" {
+		t.Fatalf("bad: %#v", driver.CreatePodConfig.Hosts)
+	}
+	if strings.Join(driver.CreatePodConfig.Ports, " ") != "5432:5432" {
+		t.Fatalf("bad: %#v", driver.CreatePodConfig.Ports)
+	}
+	if podId, _ := state.GetOk("pod_id"); podId != "pod" {
+		t.Fatalf("bad: %#v", podId)
+	}
+
+	// verify the services were started, and the health check retried
+	if driver.StartServicePod != "pod" {
+		t.Fatalf("bad: %#v", driver.StartServicePod)
+	}
+	if strings.Join(driver.StartServiceServices, " ") != "db api" {
+		t.Fatalf("bad: %#v", driver.StartServiceServices)
+	}
+	if driver.HealthCheckCount != 2 {
+		t.Fatalf("bad: %d", driver.HealthCheckCount)
+	}
+
+	// Cleanup
+	step.Cleanup(state)
+	if !driver.RemovePodCalled {
+		t.Fatal("should've removed the pod")
+	}
+	if driver.RemovePodID != "pod" {
+		t.Fatalf("bad: %#v", driver.RemovePodID)
+	}
+	if _, ok := state.GetOk("pod_id"); ok {
+		t.Fatal("should've forgotten the pod")
+	}
+}
+
+func TestStepServices_noServices(t *testing.T) {
+	state := testState(t)
+	step := new(StepServices)
+	defer step.Cleanup(state)
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	driver := state.Get("driver").(*MockDriver)
+	if driver.CreatePodCalled {
+		t.Fatal("should not have created a pod")
+	}
+}
+
+func TestStepServices_unhealthy(t *testing.T) {
+	state := testStepServicesState(t)
+	step := new(StepServices)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Services[1].HealthTimeout = 10 * time.Millisecond
+
+	driver := state.Get("driver").(*MockDriver)
+	for i := 0; i < 1000; i++ {
+		driver.HealthCheckErrs = append(driver.HealthCheckErrs, errors.New("starting"))
+	}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we have an error
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+
+	// verify the pod is removed anyway
+	step.Cleanup(state)
+	if !driver.RemovePodCalled {
+		t.Fatal("should've removed the pod")
+	}
+}
+
+func TestStepServices_keepContainer(t *testing.T) {
+	state := testStepServicesState(t)
+	step := new(StepServices)
+	defer step.Cleanup(state)
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// The build container was kept for debugging
+	state.Put("container_id", "foo")
+
+	step.Cleanup(state)
+	driver := state.Get("driver").(*MockDriver)
+	if driver.RemovePodCalled {
+		t.Fatal("should not have removed the pod")
+	}
+}
@@ -2,37 +2,49 @@ package podman
 
 import (
 	"context"
+	"fmt"
+
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
-	"strings"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
+// StepSetDefaults carries the configuration of the base image selected by
+// inherit_config over to the changes applied when committing.
 type StepSetDefaults struct{}
 
 func (s *StepSetDefaults) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	driver := state.Get("driver").(*PodmanDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+	driver := state.Get("driver").(Driver)
 	config := state.Get("config").(*Config)
 
-	// Fetch default CMD and ENTRYPOINT
-	defaultCmd, _ := driver.Cmd(config.Image)
-	defaultEntrypoint, _ := driver.Entrypoint(config.Image)
-
-	// Set defaults if not provided by the user
-	hasCmd, hasEntrypoint := false, false
+	// Instructions that can only appear once are left to the user when
+	// they are in the changes already
+	set := make(map[string]bool)
 	for _, change := range config.Changes {
-		if strings.HasPrefix(change, "CMD") {
-			hasCmd = true
-		} else if strings.HasPrefix(change, "ENTRYPOINT") {
-			hasEntrypoint = true
-		}
+		set[changeInstruction(change)] = true
 	}
 
-	if !hasCmd && defaultCmd != "" {
-		config.Changes = append(config.Changes, "CMD "+defaultCmd)
+	var instructions []string
+	for _, instruction := range config.InheritConfig {
+		if !(inheritableInstructions[instruction] && set[instruction]) {
+			instructions = append(instructions, instruction)
+		}
+	}
+	if len(instructions) == 0 {
+		return multistep.ActionContinue
 	}
-	if !hasEntrypoint && defaultEntrypoint != "" {
-		config.Changes = append(config.Changes, "ENTRYPOINT "+defaultEntrypoint)
+
+	inherited, err := driver.InheritConfig(config.Image, instructions)
+	if err != nil {
+		err := fmt.Errorf("Error inheriting the base image configuration: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
 	}
 
+	// The changes of the user come last so that they win over the
+	// inherited ones
+	config.Changes = append(inherited, config.Changes...)
 	return multistep.ActionContinue
 }
 
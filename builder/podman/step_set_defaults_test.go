@@ -0,0 +1,89 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepSetDefaults_impl(t *testing.T) {
+	var _ multistep.Step = new(StepSetDefaults)
+}
+
+func TestStepSetDefaults(t *testing.T) {
+	state := testState(t)
+	step := new(StepSetDefaults)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.InheritConfig = []string{"CMD", "ENTRYPOINT", "ENV"}
+	config.Changes = []string{"cmd [\"nginx\"]", "ENV FOO bar"}
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.InheritConfigResult = []string{"ENTRYPOINT [\"/docker-entrypoint.sh\"]", "ENV FOO baz"}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the CMD of the user wasn't inherited, but ENV still was
+	if driver.InheritConfigImage != config.Image {
+		t.Fatalf("bad: %#v", driver.InheritConfigImage)
+	}
+	if strings.Join(driver.InheritConfigInstructions, " ") != "ENTRYPOINT ENV" {
+		t.Fatalf("bad: %#v", driver.InheritConfigInstructions)
+	}
+
+	// verify the changes of the user come last
+	expected := []string{
+		"ENTRYPOINT [\"/docker-entrypoint.sh\"]",
+		"ENV FOO baz",
+		"cmd [\"nginx\"]",
+		"ENV FOO bar",
+	}
+	if strings.Join(config.Changes, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("bad: %#v", config.Changes)
+	}
+}
+
+func TestStepSetDefaults_nothing(t *testing.T) {
+	state := testState(t)
+	step := new(StepSetDefaults)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.InheritConfig = nil
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	driver := state.Get("driver").(*MockDriver)
+	if driver.InheritConfigCalled {
+		t.Fatal("should not have inherited")
+	}
+}
+
+func TestStepSetDefaults_error(t *testing.T) {
+	state := testState(t)
+	step := new(StepSetDefaults)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.InheritConfigErr = errors.New("foo")
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we have an error
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,38 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepStop stops the container so that it can be committed or exported
+// while nothing is writing to its filesystem.
+type StepStop struct{}
+
+func (s *StepStop) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	config, ok := state.Get("config").(*Config)
+	if !ok {
+		err := fmt.Errorf("error encountered obtaining podman config")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	driver := state.Get("driver").(Driver)
+	containerId := state.Get("container_id").(string)
+
+	ui.Say("Stopping the container before snapshotting it")
+	if err := driver.StopContainer(containerId, config.StopTimeout); err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepStop) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,63 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func testStepStopState(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	state.Put("container_id", "foo")
+	return state
+}
+
+func TestStepStop_impl(t *testing.T) {
+	var _ multistep.Step = new(StepStop)
+}
+
+func TestStepStop(t *testing.T) {
+	state := testStepStopState(t)
+	step := new(StepStop)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(*MockDriver)
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we did the right thing
+	if !driver.StopCalled {
+		t.Fatal("should've stopped")
+	}
+	if driver.StopID != "foo" {
+		t.Fatalf("bad: %#v", driver.StopID)
+	}
+	if driver.StopTimeout != config.StopTimeout {
+		t.Fatalf("bad: %#v", driver.StopTimeout)
+	}
+}
+
+func TestStepStop_error(t *testing.T) {
+	state := testStepStopState(t)
+	step := new(StepStop)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.StopError = errors.New("foo")
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we have an error
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
@@ -74,7 +75,52 @@ func (s *StepTempDir) Run(ctx context.Context, state multistep.StateBag) multist
 }
 
 func (s *StepTempDir) Cleanup(state multistep.StateBag) {
-	if s.tempDir != "" {
-		os.RemoveAll(s.tempDir)
+	if s.tempDir == "" {
+		return
 	}
+
+	// With rootless podman, the files the container created as another user
+	// than root belong to a subordinate UID on the host, and only podman
+	// unshare can remove them.
+	if err := os.RemoveAll(s.tempDir); err != nil {
+		log.Printf("[DEBUG] Error removing the temporary directory, retrying with podman unshare: %s", err)
+		driver := state.Get("driver").(Driver)
+		if err := driver.UnshareRemoveAll(s.tempDir); err != nil {
+			log.Printf("[WARN] %s", err)
+		}
+	}
+
+	if leftovers := listFiles(s.tempDir, 5); len(leftovers) > 0 {
+		ui := state.Get("ui").(packersdk.Ui)
+		ui.Error(fmt.Sprintf("The temporary directory %s couldn't be removed, files left include: %s",
+			s.tempDir, strings.Join(leftovers, ", ")))
+		ui.Message(fmt.Sprintf("Remove it when done with: podman unshare rm -rf %s", s.tempDir))
+	}
+
+	// Reset the temp dir so that we're idempotent
+	s.tempDir = ""
+}
+
+// listFiles returns up to max paths found under dir, relative to it, or dir
+// itself if it is all that is left.
+func listFiles(dir string, max int) []string {
+	if _, err := os.Lstat(dir); os.IsNotExist(err) {
+		return nil
+	}
+
+	files := []string{}
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if len(files) == max {
+			return filepath.SkipDir
+		}
+		if path != dir {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if len(files) == 0 {
+		return []string{dir}
+	}
+	return files
 }
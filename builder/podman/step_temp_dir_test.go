@@ -1,11 +1,17 @@
 package podman
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
 func TestStepTempDir_impl(t *testing.T) {
@@ -50,3 +56,68 @@ func testStepTempDir_impl(t *testing.T) string {
 func TestStepTempDir(t *testing.T) {
 	testStepTempDir_impl(t)
 }
+
+func TestStepTempDir_leftovers(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can remove any file")
+	}
+
+	state := testState(t)
+	step := new(StepTempDir)
+	defer step.Cleanup(state)
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// A directory the user can't write to, like one created by a
+	// subordinate UID in a rootless container
+	dir := state.Get("temp_dir").(string)
+	locked := filepath.Join(dir, "locked")
+	if err := os.Mkdir(locked, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(locked, "file"), nil, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := os.Chmod(locked, 0555); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Chmod(locked, 0755)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.UnshareRemoveAllErr = errors.New("not rootless")
+
+	// Cleanup falls back to podman unshare, then reports what is left
+	step.Cleanup(state)
+	if driver.UnshareRemoveAllPath != dir {
+		t.Fatalf("bad: %#v", driver.UnshareRemoveAllPath)
+	}
+	ui := state.Get("ui").(*packersdk.BasicUi)
+	if out := ui.Writer.(*bytes.Buffer).String(); !strings.Contains(out, "locked") {
+		t.Fatalf("bad: %s", out)
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a", "b", "c"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	if files := listFiles(dir, 2); strings.Join(files, " ") != "a b" {
+		t.Fatalf("bad: %#v", files)
+	}
+	if files := listFiles(filepath.Join(dir, "missing"), 2); len(files) != 0 {
+		t.Fatalf("bad: %#v", files)
+	}
+}
@@ -0,0 +1,89 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepVerifySecrets makes sure that the provisioners didn't copy any of the
+// secrets into the container filesystem before it is committed or exported.
+type StepVerifySecrets struct{}
+
+func (s *StepVerifySecrets) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	config, ok := state.Get("config").(*Config)
+	if !ok {
+		err := fmt.Errorf("error encountered obtaining podman config")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if len(config.Secrets) == 0 {
+		return multistep.ActionContinue
+	}
+
+	driver := state.Get("driver").(Driver)
+	containerId := state.Get("container_id").(string)
+
+	ui.Say("Checking that no secret is left in the container filesystem...")
+	found, err := scanContainer(driver, containerId, config.Secrets)
+	if err != nil {
+		err := fmt.Errorf("Error checking the container filesystem for secrets: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if len(found) > 0 {
+		var leaks []string
+		for name, paths := range found {
+			leaks = append(leaks, fmt.Sprintf("secret %s in %s", name, strings.Join(paths, ", ")))
+		}
+		sort.Strings(leaks)
+		err := fmt.Errorf("Secrets were found in the container filesystem: %s",
+			strings.Join(leaks, "; "))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepVerifySecrets) Cleanup(state multistep.StateBag) {}
+
+// scanContainer streams the filesystem of the container into findSecrets.
+func scanContainer(driver Driver, containerId string, secrets []SecretConfig) (map[string][]string, error) {
+	r, w := io.Pipe()
+	exportErr := make(chan error, 1)
+	go func() {
+		err := driver.Export(containerId, w)
+		w.CloseWithError(err)
+		exportErr <- err
+	}()
+
+	found, err := findSecrets(r, secrets)
+
+	// Drain what follows the end of the archive, or unblock the export if
+	// reading it failed.
+	if err == nil {
+		_, err = io.Copy(ioutil.Discard, r)
+	}
+	r.Close()
+	if eErr := <-exportErr; err == nil {
+		err = eErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
@@ -0,0 +1,88 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func testStepVerifySecretsState(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	state.Put("container_id", "foo")
+	config := state.Get("config").(*Config)
+	config.Secrets = []SecretConfig{{Name: "token", value: []byte("s3cr3t-token")}}
+	return state
+}
+
+func TestStepVerifySecrets_impl(t *testing.T) {
+	var _ multistep.Step = new(StepVerifySecrets)
+}
+
+func TestStepVerifySecrets(t *testing.T) {
+	state := testStepVerifySecretsState(t)
+	step := new(StepVerifySecrets)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExportReader = testSecretsTar(t, map[string][]byte{
+		"run/secrets/token": nil,
+		"etc/hostname":      []byte("build"),
+	})
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the container was scanned
+	if driver.ExportID != "foo" {
+		t.Fatalf("bad: %#v", driver.ExportID)
+	}
+}
+
+func TestStepVerifySecrets_leak(t *testing.T) {
+	state := testStepVerifySecretsState(t)
+	step := new(StepVerifySecrets)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExportReader = testSecretsTar(t, map[string][]byte{
+		"root/token": []byte("s3cr3t-token\n"),
+	})
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the error names the file
+	err, ok := state.GetOk("error")
+	if !ok {
+		t.Fatal("should have error")
+	}
+	if !strings.Contains(err.(error).Error(), "secret token in /root/token") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestStepVerifySecrets_error(t *testing.T) {
+	state := testStepVerifySecretsState(t)
+	step := new(StepVerifySecrets)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExportError = errors.New("foo")
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify we have an error
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,147 @@
+package podman
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepWaitReady waits for the container to be ready before provisioning:
+// until ready_command succeeds, the health check of the image passes, or
+// systemd finishes booting when it is the init of the container.
+type StepWaitReady struct {
+	// pollInterval is how long to wait between two checks. It defaults to
+	// one second and is only changed in tests.
+	pollInterval time.Duration
+}
+
+func (s *StepWaitReady) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+	config, ok := state.Get("config").(*Config)
+	if !ok {
+		err := fmt.Errorf("error encountered obtaining podman config")
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	driver := state.Get("driver").(Driver)
+	containerId := state.Get("container_id").(string)
+
+	if s.pollInterval == 0 {
+		s.pollInterval = time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.ReadyTimeout)
+	defer cancel()
+
+	var err error
+	switch {
+	case config.ReadyCommand != "":
+		ui.Say("Waiting for the container to be ready...")
+		err = s.poll(ctx, func() error {
+			_, err := driver.Exec(ctx, containerId, []string{"/bin/sh", "-c", config.ReadyCommand})
+			return err
+		})
+	case config.ReadyHealthcheck:
+		ui.Say("Waiting for the container to be healthy...")
+		err = s.poll(ctx, func() error {
+			return driver.HealthCheck(containerId)
+		})
+	case config.Systemd != "false":
+		// Whether systemd runs depends on the command of the container
+		init, initErr := driver.Exec(ctx, containerId, []string{"cat", "/proc/1/comm"})
+		if initErr != nil || init != "systemd" {
+			return multistep.ActionContinue
+		}
+
+		ui.Say("Waiting for systemd to finish booting...")
+		err = s.waitSystemd(ctx, driver, containerId)
+	default:
+		return multistep.ActionContinue
+	}
+
+	if _, ok := err.(*systemDegradedError); ok {
+		err := fmt.Errorf("Container isn't ready: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if err != nil {
+		err := fmt.Errorf("Container isn't ready after %s: %s", config.ReadyTimeout, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepWaitReady) Cleanup(state multistep.StateBag) {}
+
+// poll runs check until it succeeds, the system is degraded, or ctx is done,
+// returning the last error of check in the latter cases.
+func (s *StepWaitReady) poll(ctx context.Context, check func() error) error {
+	for {
+		err := check()
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(*systemDegradedError); ok {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// waitSystemd waits for systemd to report that the system is running, and
+// fails with the list of the failed units if it is degraded.
+func (s *StepWaitReady) waitSystemd(ctx context.Context, driver Driver, id string) error {
+	return s.poll(ctx, func() error {
+		// --wait blocks until the boot is over with systemd 240 or newer,
+		// older versions answer right away and are polled.
+		status, err := driver.Exec(ctx, id, []string{"systemctl", "is-system-running", "--wait"})
+		switch status {
+		case "running":
+			return nil
+		case "degraded":
+			failed, _ := driver.Exec(ctx, id, []string{"systemctl", "--failed", "--no-legend", "--plain"})
+			return &systemDegradedError{Units: parseFailedUnits(failed)}
+		}
+		if status == "" {
+			return err
+		}
+		return fmt.Errorf("system is %s", status)
+	})
+}
+
+// systemDegradedError is returned when systemd is done booting but some
+// units failed, which waiting longer won't fix.
+type systemDegradedError struct {
+	Units []string
+}
+
+func (e *systemDegradedError) Error() string {
+	return fmt.Sprintf("system is degraded, failed units: %s", strings.Join(e.Units, ", "))
+}
+
+// parseFailedUnits returns the names of the units listed by `systemctl
+// --failed --no-legend --plain`.
+func parseFailedUnits(output string) []string {
+	var units []string
+	for _, line := range strings.Split(output, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			units = append(units, fields[0])
+		}
+	}
+	return units
+}
@@ -0,0 +1,169 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func testStepWaitReadyState(t *testing.T) multistep.StateBag {
+	state := testState(t)
+	state.Put("container_id", "foo")
+	return state
+}
+
+func TestStepWaitReady_impl(t *testing.T) {
+	var _ multistep.Step = new(StepWaitReady)
+}
+
+func TestStepWaitReady_noSystemd(t *testing.T) {
+	state := testStepWaitReadyState(t)
+	step := &StepWaitReady{pollInterval: time.Millisecond}
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExecResults = []MockExecResult{{Output: "sh"}}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify only the init was looked at
+	if len(driver.ExecCommands) != 1 {
+		t.Fatalf("bad: %#v", driver.ExecCommands)
+	}
+}
+
+func TestStepWaitReady_systemdDisabled(t *testing.T) {
+	state := testStepWaitReadyState(t)
+	step := &StepWaitReady{pollInterval: time.Millisecond}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.Systemd = "false"
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	driver := state.Get("driver").(*MockDriver)
+	if len(driver.ExecCommands) != 0 {
+		t.Fatalf("bad: %#v", driver.ExecCommands)
+	}
+}
+
+func TestStepWaitReady_systemd(t *testing.T) {
+	state := testStepWaitReadyState(t)
+	step := &StepWaitReady{pollInterval: time.Millisecond}
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExecResults = []MockExecResult{
+		{Output: "systemd"},
+		{Output: "starting", Err: errors.New("exit status 1")},
+		{Output: "running"},
+	}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify systemd was polled until it was running
+	if len(driver.ExecCommands) != 3 {
+		t.Fatalf("bad: %#v", driver.ExecCommands)
+	}
+	expected := []string{"systemctl", "is-system-running", "--wait"}
+	if !reflect.DeepEqual(driver.ExecCommands[2], expected) {
+		t.Fatalf("bad: %#v", driver.ExecCommands[2])
+	}
+}
+
+func TestStepWaitReady_systemdDegraded(t *testing.T) {
+	state := testStepWaitReadyState(t)
+	step := &StepWaitReady{pollInterval: time.Millisecond}
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExecResults = []MockExecResult{
+		{Output: "systemd"},
+		{Output: "degraded", Err: errors.New("exit status 1")},
+		{Output: "getty@tty1.service loaded failed failed Getty on tty1\n" +
+			"systemd-remount-fs.service loaded failed failed Remount Root and Kernel File Systems"},
+	}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the error lists the failed units
+	err, ok := state.GetOk("error")
+	if !ok {
+		t.Fatal("should have error")
+	}
+	if !strings.Contains(err.(error).Error(), "failed units: getty@tty1.service, systemd-remount-fs.service") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestStepWaitReady_readyCommand(t *testing.T) {
+	state := testStepWaitReadyState(t)
+	step := &StepWaitReady{pollInterval: time.Millisecond}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ReadyCommand = "test -S /run/app.sock"
+
+	driver := state.Get("driver").(*MockDriver)
+	driver.ExecResults = []MockExecResult{{Err: errors.New("exit status 1")}}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the command was retried
+	if len(driver.ExecCommands) != 2 {
+		t.Fatalf("bad: %#v", driver.ExecCommands)
+	}
+	expected := []string{"/bin/sh", "-c", "test -S /run/app.sock"}
+	if !reflect.DeepEqual(driver.ExecCommands[1], expected) {
+		t.Fatalf("bad: %#v", driver.ExecCommands[1])
+	}
+}
+
+func TestStepWaitReady_healthcheckTimeout(t *testing.T) {
+	state := testStepWaitReadyState(t)
+	step := &StepWaitReady{pollInterval: time.Millisecond}
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ReadyHealthcheck = true
+	config.ReadyTimeout = 10 * time.Millisecond
+
+	driver := state.Get("driver").(*MockDriver)
+	for i := 0; i < 1000; i++ {
+		driver.HealthCheckErrs = append(driver.HealthCheckErrs, errors.New("unhealthy"))
+	}
+
+	// run the step
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	// verify the health check was polled
+	if driver.HealthCheckID != "foo" {
+		t.Fatalf("bad: %#v", driver.HealthCheckID)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
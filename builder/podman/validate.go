@@ -0,0 +1,175 @@
+package podman
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// nameRegexp matches the names podman accepts for volumes, secrets and
+// containers.
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// linuxCapabilities are the capabilities cap_add and cap_drop accept, without
+// their CAP_ prefix.
+var linuxCapabilities = map[string]bool{
+	"ALL": true, "AUDIT_CONTROL": true, "AUDIT_READ": true, "AUDIT_WRITE": true,
+	"BLOCK_SUSPEND": true, "BPF": true, "CHECKPOINT_RESTORE": true,
+	"CHOWN": true, "DAC_OVERRIDE": true, "DAC_READ_SEARCH": true,
+	"FOWNER": true, "FSETID": true, "IPC_LOCK": true, "IPC_OWNER": true,
+	"KILL": true, "LEASE": true, "LINUX_IMMUTABLE": true, "MAC_ADMIN": true,
+	"MAC_OVERRIDE": true, "MKNOD": true, "NET_ADMIN": true,
+	"NET_BIND_SERVICE": true, "NET_BROADCAST": true, "NET_RAW": true,
+	"PERFMON": true, "SETFCAP": true, "SETGID": true, "SETPCAP": true,
+	"SETUID": true, "SYSLOG": true, "SYS_ADMIN": true, "SYS_BOOT": true,
+	"SYS_CHROOT": true, "SYS_MODULE": true, "SYS_NICE": true,
+	"SYS_PACCT": true, "SYS_PTRACE": true, "SYS_RAWIO": true,
+	"SYS_RESOURCE": true, "SYS_TIME": true, "SYS_TTY_CONFIG": true,
+	"WAKE_ALARM": true,
+}
+
+// usernsModes are the modes of podman run --userns, some of which take
+// options after a colon.
+var usernsModes = map[string]bool{
+	"auto": true, "container": true, "host": true, "keep-id": true,
+	"nomap": true, "ns": true, "private": true,
+}
+
+// validate checks the options podman would only reject once the container
+// is started, returning every problem found.
+func (c *Config) validate() []error {
+	var errs []error
+
+	switch c.Systemd {
+	case "true", "false", "always":
+	default:
+		errs = append(errs, fmt.Errorf(
+			"systemd must be one of true, false or always, got %q", c.Systemd))
+	}
+
+	if c.Userns != "" {
+		mode := strings.SplitN(c.Userns, ":", 2)[0]
+		if !usernsModes[mode] {
+			errs = append(errs, fmt.Errorf(
+				"userns must be one of auto, container, host, keep-id, nomap, ns or private, got %q", c.Userns))
+		}
+	}
+
+	for _, device := range c.Device {
+		if err := validateDevice(device); err != nil {
+			errs = append(errs, fmt.Errorf("device %q: %s", device, err))
+		}
+	}
+
+	for _, tmpfs := range c.TmpFs {
+		if err := validateTmpFs(tmpfs); err != nil {
+			errs = append(errs, fmt.Errorf("tmpfs %q: %s", tmpfs, err))
+		}
+	}
+
+	for _, field := range []struct {
+		name string
+		caps []string
+	}{{"cap_add", c.CapAdd}, {"cap_drop", c.CapDrop}} {
+		for _, capability := range field.caps {
+			name := strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+			if !linuxCapabilities[name] {
+				errs = append(errs, fmt.Errorf("%s: unknown capability %q", field.name, capability))
+			}
+		}
+	}
+
+	// Sort the volumes so that errors are reported in a stable order
+	hosts := make([]string, 0, len(c.Volumes))
+	for host := range c.Volumes {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		if err := validateVolume(host, c.Volumes[host]); err != nil {
+			errs = append(errs, fmt.Errorf("volumes %q: %s", host, err))
+		}
+	}
+
+	return errs
+}
+
+// cdiDeviceRegexp matches the fully qualified names of CDI devices,
+// vendor/class=name, which podman run --device accepts too.
+var cdiDeviceRegexp = regexp.MustCompile(
+	`^[a-zA-Z0-9]([a-zA-Z0-9_.-]*[a-zA-Z0-9])?/[a-zA-Z0-9]([a-zA-Z0-9_-]*[a-zA-Z0-9])?=[a-zA-Z0-9]([a-zA-Z0-9_.:-]*[a-zA-Z0-9])?$`)
+
+// validateDevice checks a device in the podman run --device format,
+// host[:container][:permissions], or the name of a CDI device.
+func validateDevice(device string) error {
+	if cdiDeviceRegexp.MatchString(device) {
+		return nil
+	}
+
+	parts := strings.Split(device, ":")
+	if len(parts) > 3 {
+		return fmt.Errorf("expected host[:container][:permissions]")
+	}
+	if !strings.HasPrefix(parts[0], "/") {
+		return fmt.Errorf("the host device must be an absolute path or a CDI device name")
+	}
+
+	switch len(parts) {
+	case 2:
+		// The second part is either the permissions or the container path
+		if !isDevicePermissions(parts[1]) && !strings.HasPrefix(parts[1], "/") {
+			return fmt.Errorf("%q is neither an absolute path nor permissions made of r, w and m", parts[1])
+		}
+	case 3:
+		if !strings.HasPrefix(parts[1], "/") {
+			return fmt.Errorf("the container device must be an absolute path")
+		}
+		if !isDevicePermissions(parts[2]) {
+			return fmt.Errorf("permissions must be made of r, w and m, got %q", parts[2])
+		}
+	}
+
+	return nil
+}
+
+func isDevicePermissions(s string) bool {
+	if s == "" || len(s) > 3 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("rwm", c) || strings.Count(s, string(c)) > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// validateTmpFs checks a tmpfs in the podman run --tmpfs format,
+// path[:option,...].
+func validateTmpFs(tmpfs string) error {
+	parts := strings.SplitN(tmpfs, ":", 2)
+	if !strings.HasPrefix(parts[0], "/") {
+		return fmt.Errorf("the path must be absolute")
+	}
+	if len(parts) == 2 {
+		for _, option := range strings.Split(parts[1], ",") {
+			if option == "" {
+				return fmt.Errorf("options can't be empty")
+			}
+		}
+	}
+	return nil
+}
+
+// validateVolume checks a host path, or named volume, and the container
+// path it is mounted at, possibly followed by mount options.
+func validateVolume(host, container string) error {
+	if host == "" {
+		return fmt.Errorf("the host path can't be empty")
+	}
+	if !strings.HasPrefix(strings.SplitN(container, ":", 2)[0], "/") {
+		return fmt.Errorf("the container path must be absolute, got %q", container)
+	}
+	return nil
+}
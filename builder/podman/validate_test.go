@@ -0,0 +1,86 @@
+package podman
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	c := &Config{
+		Systemd: "yes",
+		Userns:  "keep_id",
+		Device:  []string{"/dev/fuse", "/dev/sdc:/dev/xvdc:rwx", "sdc"},
+		TmpFs:   []string{"/run:rw,size=64m", "tmp"},
+		CapAdd:  []string{"SYS_ADMIN", "cap_net_admin", "CAP_SYS_MAGIC"},
+		CapDrop: []string{"all"},
+		Volumes: map[string]string{"/host": "/guest:ro", "/other": "guest"},
+	}
+
+	// Every problem is reported at once, naming its option
+	errs := c.validate()
+	expected := []string{
+		`systemd must be one of true, false or always, got "yes"`,
+		`userns must be one of`,
+		`device "/dev/sdc:/dev/xvdc:rwx": permissions`,
+		`device "sdc": the host device must be an absolute path`,
+		`tmpfs "tmp": the path must be absolute`,
+		`cap_add: unknown capability "CAP_SYS_MAGIC"`,
+		`volumes "/other": the container path must be absolute`,
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("bad: %#v", errs)
+	}
+	for i, err := range errs {
+		if !strings.HasPrefix(err.Error(), expected[i]) {
+			t.Fatalf("bad error %d: %s", i, err)
+		}
+	}
+
+	c = &Config{Systemd: "always", Userns: "keep-id:uid=1000"}
+	if errs := c.validate(); len(errs) != 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
+func TestValidateDevice(t *testing.T) {
+	for _, device := range []string{
+		"/dev/fuse",
+		"/dev/sdc:/dev/xvdc",
+		"/dev/sdc:rw",
+		"/dev/sdc:/dev/xvdc:rwm",
+		"nvidia.com/gpu=all",
+		"vendor.example.com/net=eth0:1",
+	} {
+		if err := validateDevice(device); err != nil {
+			t.Errorf("%s: %s", device, err)
+		}
+	}
+
+	for _, device := range []string{
+		"",
+		"/dev/sdc:xvdc",
+		"/dev/sdc:/dev/xvdc:rr",
+		"/dev/sdc:/dev/xvdc:rw:m",
+		"nvidia.com/gpu",
+		"nvidia.com=all",
+		"nvidia.com/gpu=",
+	} {
+		if err := validateDevice(device); err == nil {
+			t.Errorf("%s: should error", device)
+		}
+	}
+}
+
+func TestValidateTmpFs(t *testing.T) {
+	for _, tmpfs := range []string{"/run", "/tmp:rw,noexec,size=64m"} {
+		if err := validateTmpFs(tmpfs); err != nil {
+			t.Errorf("%s: %s", tmpfs, err)
+		}
+	}
+
+	for _, tmpfs := range []string{"run", "/tmp:", "/tmp:rw,,noexec"} {
+		if err := validateTmpFs(tmpfs); err == nil {
+			t.Errorf("%s: should error", tmpfs)
+		}
+	}
+}
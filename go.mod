@@ -6,7 +6,9 @@ require (
 	github.com/hashicorp/go-version v1.7.0
 	github.com/hashicorp/hcl/v2 v2.20.1
 	github.com/hashicorp/packer-plugin-sdk v0.5.2
+	github.com/klauspost/compress v1.11.2
 	github.com/mitchellh/mapstructure v1.5.0
+	github.com/ulikunitz/xz v0.5.10
 	github.com/zclconf/go-cty v1.14.2
 )
 